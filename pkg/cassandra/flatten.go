@@ -0,0 +1,104 @@
+package cassandra
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// flattenColumns returns names, grafana field types and units of the result columns
+// where every UDT field and tuple element is presented as a separate column.
+// UDT fields are named as `column.field`, tuple elements as `column[0]`.
+// Nested UDTs and tuples are flattened recursively.
+func flattenColumns(columnInfo []gocql.ColumnInfo, exactNumbers bool) ([]string, map[string]data.FieldType, map[string]string) {
+	names := make([]string, 0, len(columnInfo))
+	types := make(map[string]data.FieldType, len(columnInfo))
+	units := make(map[string]string)
+	for _, col := range columnInfo {
+		walkColumn(col.Name, col.TypeInfo, func(name string, info gocql.TypeInfo) {
+			names = append(names, name)
+			types[name] = fieldType(info, exactNumbers)
+			if unit := fieldUnit(info); unit != "" {
+				units[name] = unit
+			}
+		})
+	}
+
+	return names, types, units
+}
+
+// flattenValues maps values of UDT and tuple columns to the flattened column
+// names produced by flattenColumns. Members of a NULL UDT or tuple are nil.
+func flattenValues(columnInfo []gocql.ColumnInfo, values map[string]interface{}) map[string]interface{} {
+	flattened := make(map[string]interface{}, len(values))
+	for _, col := range columnInfo {
+		flattenValue(col.Name, col.TypeInfo, values[col.Name], flattened)
+	}
+
+	return flattened
+}
+
+func flattenValue(name string, info gocql.TypeInfo, val interface{}, flattened map[string]interface{}) {
+	switch t := info.(type) {
+	case gocql.UDTTypeInfo:
+		udt, _ := val.(map[string]interface{})
+		for _, elem := range t.Elements {
+			flattenValue(udtFieldName(name, elem.Name), elem.Type, udt[elem.Name], flattened)
+		}
+	case gocql.TupleTypeInfo:
+		tuple, _ := val.([]interface{})
+		for i, elem := range t.Elems {
+			var elemVal interface{}
+			if i < len(tuple) {
+				elemVal = tuple[i]
+			}
+			flattenValue(gocql.TupleColumnName(name, i), elem, elemVal, flattened)
+		}
+	default:
+		flattened[name] = val
+	}
+}
+
+// udtValue is a scan destination of a UDT column. Unlike map[string]interface{}
+// used by gocql, which unmarshals NULL members to zero values, NULL members
+// are nil, and nested UDTs are udtValue too.
+type udtValue map[string]interface{}
+
+// UnmarshalUDT implements gocql.UDTUnmarshaler.
+func (u *udtValue) UnmarshalUDT(name string, info gocql.TypeInfo, data []byte) error {
+	if *u == nil {
+		*u = make(udtValue)
+	}
+
+	dest, err := newScanValue(info)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", name, err)
+	}
+	if err := gocql.Unmarshal(info, data, dest); err != nil {
+		return fmt.Errorf("field %s: %w", name, err)
+	}
+	(*u)[name] = dereference(dest)
+
+	return nil
+}
+
+// walkColumn calls fn for every leaf (not UDT and not tuple) member of the column.
+func walkColumn(name string, info gocql.TypeInfo, fn func(name string, info gocql.TypeInfo)) {
+	switch t := info.(type) {
+	case gocql.UDTTypeInfo:
+		for _, elem := range t.Elements {
+			walkColumn(udtFieldName(name, elem.Name), elem.Type, fn)
+		}
+	case gocql.TupleTypeInfo:
+		for i, elem := range t.Elems {
+			walkColumn(gocql.TupleColumnName(name, i), elem, fn)
+		}
+	default:
+		fn(name, info)
+	}
+}
+
+func udtFieldName(column, field string) string {
+	return fmt.Sprintf("%s.%s", column, field)
+}
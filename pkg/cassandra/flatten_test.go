@@ -0,0 +1,105 @@
+package cassandra
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func testColumns() []gocql.ColumnInfo {
+	location := gocql.UDTTypeInfo{
+		NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+		Name:       "location",
+		Elements: []gocql.UDTField{
+			{Name: "lat", Type: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+			{Name: "lon", Type: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+		},
+	}
+
+	return []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: gocql.NewNativeType(4, gocql.TypeText, "")},
+		{Name: "m", TypeInfo: gocql.UDTTypeInfo{
+			NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+			Name:       "measurement",
+			Elements: []gocql.UDTField{
+				{Name: "value", Type: gocql.NewNativeType(4, gocql.TypeInt, "")},
+				{Name: "unit", Type: gocql.NewNativeType(4, gocql.TypeText, "")},
+				{Name: "location", Type: location},
+			},
+		}},
+		{Name: "range", TypeInfo: gocql.TupleTypeInfo{
+			NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+			Elems: []gocql.TypeInfo{
+				gocql.NewNativeType(4, gocql.TypeDouble, ""),
+				gocql.NewNativeType(4, gocql.TypeDouble, ""),
+			},
+		}},
+	}
+}
+
+func Test_flattenColumns(t *testing.T) {
+	names, types, units := flattenColumns(testColumns(), false)
+
+	assert.Equal(t, []string{"id", "m.value", "m.unit", "m.location.lat", "m.location.lon", "range[0]", "range[1]"}, names)
+	assert.Equal(t, map[string]data.FieldType{
+		"id":             data.FieldTypeString,
+		"m.value":        data.FieldTypeInt64,
+		"m.unit":         data.FieldTypeString,
+		"m.location.lat": data.FieldTypeFloat64,
+		"m.location.lon": data.FieldTypeFloat64,
+		"range[0]":       data.FieldTypeFloat64,
+		"range[1]":       data.FieldTypeFloat64,
+	}, types)
+	assert.Empty(t, units)
+}
+
+func Test_flattenValues(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name: "values",
+			input: map[string]interface{}{
+				"id": "1",
+				"m": map[string]interface{}{
+					"value":    2,
+					"unit":     "C",
+					"location": map[string]interface{}{"lat": 52.52, "lon": 13.4},
+				},
+				"range": []interface{}{0.1, 0.9},
+			},
+			want: map[string]interface{}{
+				"id":             "1",
+				"m.value":        2,
+				"m.unit":         "C",
+				"m.location.lat": 52.52,
+				"m.location.lon": 13.4,
+				"range[0]":       0.1,
+				"range[1]":       0.9,
+			},
+		},
+		{
+			name:  "nulls",
+			input: map[string]interface{}{"id": "1", "m": nil, "range": []interface{}{nil, 0.9}},
+			want: map[string]interface{}{
+				"id":             "1",
+				"m.value":        nil,
+				"m.unit":         nil,
+				"m.location.lat": nil,
+				"m.location.lon": nil,
+				"range[0]":       nil,
+				"range[1]":       0.9,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, flattenValues(testColumns(), tc.input))
+		})
+	}
+}
@@ -1,50 +1,242 @@
 package cassandra
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"net"
+	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"gopkg.in/inf.v0"
+)
+
+// approximate lengths of duration units which cannot be converted to
+// nanoseconds exactly, since months and days have variable length.
+const (
+	durationDay   = 24 * time.Hour
+	durationMonth = 30 * durationDay
 )
 
 type Row struct {
 	Columns []string
 	Fields  map[string]interface{}
+	// Types contains grafana field types of the row columns resolved from the
+	// CQL column metadata. Types are shared by all rows of the same result and
+	// allow to build frame fields even if the first row contains NULLs.
+	Types map[string]data.FieldType
+	// Collections contains normalized values of map, set and list columns,
+	// which are rendered as JSON strings in Fields. Lists and sets are
+	// stored as []interface{}, maps as map[string]interface{}.
+	Collections map[string]interface{}
+	// Units contains grafana units of the columns which values have been
+	// converted from CQL types, e.g. `ns` for durations.
+	Units map[string]string
 }
 
 // normalize checks the type of returned field and in case if
 // it is not supported by grafana tries to convert it to a supported type.
 // If some field has type that cannot be converted then error is returned.
+// NULL values are kept as nil. Decimal and varint values are converted
+// to float64, unless exactNumbers is set, then they are kept as strings.
 // Type mappings are based on these:
 // Cassandra gocql types: https://github.com/gocql/gocql/blob/master/marshal.go#L164
 // Grafana field types: https://github.com/grafana/grafana-plugin-sdk-go/blob/main/data/field.go#L39
-func (r *Row) normalize() error {
+func (r *Row) normalize(exactNumbers bool) error {
 	for _, colName := range r.Columns {
-		switch v := r.Fields[colName].(type) {
-		case int8, int16, int32, int64, float32, float64, string, bool, time.Time:
-		// nulls are not supported yet, so just in case they will`
-		// https://github.com/gocql/gocql/issues/1248
-		case *int, *int8, *int16, *int32, *int64, *float32, *float64, *string, *bool:
-		case int:
-			r.Fields[colName] = int64(v)
-		case []byte:
-			r.Fields[colName] = string(v)
-		case net.IP:
-			r.Fields[colName] = v.String()
-		case gocql.UUID:
-			r.Fields[colName] = v.String()
-		case *big.Int:
-			if v != nil {
-				r.Fields[colName] = v.String()
-			} else {
-				r.Fields[colName] = ""
+		val := r.Fields[colName]
+		if isCollection(val) {
+			collection, err := normalizeCollection(val, exactNumbers)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", colName, err)
 			}
-		default:
-			return fmt.Errorf("field %s has unsupported type %T", colName, v)
+			if collection == nil {
+				r.Fields[colName] = nil
+				continue
+			}
+			jsonBytes, err := json.Marshal(collection)
+			if err != nil {
+				return fmt.Errorf("field %s: json.Marshal: %w", colName, err)
+			}
+			if r.Collections == nil {
+				r.Collections = make(map[string]interface{})
+			}
+			r.Collections[colName] = collection
+			r.Fields[colName] = string(jsonBytes)
+			continue
+		}
+
+		normalized, err := normalizeValue(val, exactNumbers)
+		if err != nil {
+			return fmt.Errorf("field %s has unsupported type %T", colName, val)
 		}
+		r.Fields[colName] = normalized
 	}
 
 	return nil
 }
+
+// Size returns an approximate size of the normalized row values in bytes.
+func (r *Row) Size() int {
+	size := 0
+	for _, colName := range r.Columns {
+		switch v := r.Fields[colName].(type) {
+		case nil:
+		case string:
+			size += len(v)
+		case int8, bool:
+			size++
+		case int16:
+			size += 2
+		case int32, float32:
+			size += 4
+		default:
+			size += 8
+		}
+	}
+
+	return size
+}
+
+// normalizeValue converts a single scalar value to a type supported by grafana.
+func normalizeValue(val interface{}, exactNumbers bool) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case int8, int16, int32, int64, float32, float64, string, bool, time.Time:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case []byte:
+		return string(v), nil
+	case net.IP:
+		return v.String(), nil
+	case gocql.UUID:
+		return v.String(), nil
+	case time.Duration:
+		// CQL time, nanoseconds since midnight
+		return int64(v), nil
+	case gocql.Duration:
+		return int64(v.Months)*int64(durationMonth) + int64(v.Days)*int64(durationDay) + v.Nanoseconds, nil
+	case *big.Int:
+		if v == nil {
+			return nil, nil
+		}
+		if exactNumbers {
+			return v.String(), nil
+		}
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, nil
+	case *inf.Dec:
+		if v == nil {
+			return nil, nil
+		}
+		if exactNumbers {
+			return v.String(), nil
+		}
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("decimal conversion: %w", err)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// isCollection reports whether the value is a CQL list, set or map. Byte slices
+// (blob, inet), tuples ([]interface{}) and UDTs (map[string]interface{}) are
+// not collections.
+func isCollection(val interface{}) bool {
+	switch val.(type) {
+	case nil, []byte, net.IP, []interface{}, map[string]interface{}:
+		return false
+	}
+
+	kind := reflect.TypeOf(val).Kind()
+	return kind == reflect.Slice || kind == reflect.Map
+}
+
+// normalizeCollection normalizes all elements of a list, set or map. Map keys
+// are converted to strings. NULL collection (nil slice or map) results in nil.
+func normalizeCollection(val interface{}, exactNumbers bool) (interface{}, error) {
+	rv := reflect.ValueOf(val)
+	if rv.IsNil() {
+		return nil, nil
+	}
+
+	if rv.Kind() == reflect.Slice {
+		list := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := normalizeValue(rv.Index(i).Interface(), exactNumbers)
+			if err != nil {
+				return nil, fmt.Errorf("list element: %w", err)
+			}
+			list = append(list, elem)
+		}
+		return list, nil
+	}
+
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, err := normalizeValue(iter.Key().Interface(), exactNumbers)
+		if err != nil {
+			return nil, fmt.Errorf("map key: %w", err)
+		}
+		elem, err := normalizeValue(iter.Value().Interface(), exactNumbers)
+		if err != nil {
+			return nil, fmt.Errorf("map value: %w", err)
+		}
+		m[fmt.Sprintf("%v", key)] = elem
+	}
+
+	return m, nil
+}
+
+// fieldType returns a non-nullable grafana field type of the values produced
+// by normalize for the given CQL type. FieldTypeUnknown is returned for the
+// types which are not supported.
+func fieldType(info gocql.TypeInfo, exactNumbers bool) data.FieldType {
+	switch info.Type() {
+	case gocql.TypeAscii, gocql.TypeText, gocql.TypeVarchar, gocql.TypeBlob,
+		gocql.TypeInet, gocql.TypeUUID, gocql.TypeTimeUUID,
+		gocql.TypeList, gocql.TypeSet, gocql.TypeMap:
+		return data.FieldTypeString
+	case gocql.TypeBigInt, gocql.TypeCounter, gocql.TypeInt, gocql.TypeTime, gocql.TypeDuration:
+		return data.FieldTypeInt64
+	case gocql.TypeSmallInt:
+		return data.FieldTypeInt16
+	case gocql.TypeTinyInt:
+		return data.FieldTypeInt8
+	case gocql.TypeFloat:
+		return data.FieldTypeFloat32
+	case gocql.TypeDouble:
+		return data.FieldTypeFloat64
+	case gocql.TypeDecimal, gocql.TypeVarint:
+		if exactNumbers {
+			return data.FieldTypeString
+		}
+		return data.FieldTypeFloat64
+	case gocql.TypeBoolean:
+		return data.FieldTypeBool
+	case gocql.TypeTimestamp, gocql.TypeDate:
+		return data.FieldTypeTime
+	default:
+		return data.FieldTypeUnknown
+	}
+}
+
+// fieldUnit returns a grafana unit of the values produced by normalize
+// for the given CQL type or empty string if there is no specific unit.
+func fieldUnit(info gocql.TypeInfo) string {
+	switch info.Type() {
+	case gocql.TypeTime, gocql.TypeDuration:
+		return "ns"
+	default:
+		return ""
+	}
+}
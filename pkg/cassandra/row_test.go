@@ -8,15 +8,18 @@ import (
 	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/inf.v0"
 )
 
 func TestRow_normalize(t *testing.T) {
 	testCases := []struct {
-		name    string
-		input   *Row
-		want    *Row
-		wantErr error
+		name         string
+		input        *Row
+		exactNumbers bool
+		want         *Row
+		wantErr      error
 	}{
 		{
 			name:    "empty",
@@ -46,7 +49,7 @@ func TestRow_normalize(t *testing.T) {
 				Columns: []string{"field1", "id", "time", "value"},
 				Fields:  map[string]interface{}{"field1": nil, "id": "id", "time": time.UnixMilli(1257894000000).UTC(), "value": 0.1},
 			},
-			wantErr: fmt.Errorf("field %s has unsupported type %T", "field1", nil),
+			wantErr: nil,
 		},
 		{
 			name: "normal row with nil in the end",
@@ -58,7 +61,7 @@ func TestRow_normalize(t *testing.T) {
 				Columns: []string{"id", "time", "value", "field1"},
 				Fields:  map[string]interface{}{"id": "id", "time": time.UnixMilli(1257894000000).UTC(), "value": 0.1, "field1": nil},
 			},
-			wantErr: fmt.Errorf("field %s has unsupported type %T", "field1", nil),
+			wantErr: nil,
 		},
 		{
 			name: "normal row with unsupported",
@@ -92,7 +95,7 @@ func TestRow_normalize(t *testing.T) {
 			},
 			want: &Row{
 				Columns: []string{"field1", "id", "field2", "time", "field3", "value", "field4"},
-				Fields:  map[string]interface{}{"field1": "00000000-0000-0000-0000-000000000000", "id": "id", "field2": "127.0.0.1", "time": time.UnixMilli(1257894000000).UTC(), "field3": "some string", "value": 0.1, "field4": "12345"},
+				Fields:  map[string]interface{}{"field1": "00000000-0000-0000-0000-000000000000", "id": "id", "field2": "127.0.0.1", "time": time.UnixMilli(1257894000000).UTC(), "field3": "some string", "value": 0.1, "field4": float64(12345)},
 			},
 			wantErr: nil,
 		},
@@ -102,6 +105,7 @@ func TestRow_normalize(t *testing.T) {
 				Columns: []string{"id", "large_number"},
 				Fields:  map[string]interface{}{"id": "test", "large_number": new(big.Int).SetBytes([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})},
 			},
+			exactNumbers: true,
 			want: &Row{
 				Columns: []string{"id", "large_number"},
 				Fields:  map[string]interface{}{"id": "test", "large_number": "4722366482869645213695"},
@@ -116,15 +120,93 @@ func TestRow_normalize(t *testing.T) {
 			},
 			want: &Row{
 				Columns: []string{"id", "varint_field"},
-				Fields:  map[string]interface{}{"id": "test", "varint_field": ""},
+				Fields:  map[string]interface{}{"id": "test", "varint_field": nil},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "row with decimal, duration and time",
+			input: &Row{
+				Columns: []string{"id", "price", "period", "at", "day"},
+				Fields: map[string]interface{}{
+					"id":     "test",
+					"price":  inf.NewDec(12345, 2),
+					"period": gocql.Duration{Months: 1, Days: 2, Nanoseconds: 3},
+					"at":     13*time.Hour + 5*time.Minute,
+					"day":    time.Date(2009, time.November, 10, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			want: &Row{
+				Columns: []string{"id", "price", "period", "at", "day"},
+				Fields: map[string]interface{}{
+					"id":     "test",
+					"price":  123.45,
+					"period": int64(32*24*time.Hour + 3),
+					"at":     int64(13*time.Hour + 5*time.Minute),
+					"day":    time.Date(2009, time.November, 10, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "row with exact numbers",
+			input: &Row{
+				Columns: []string{"id", "price", "count", "empty"},
+				Fields:  map[string]interface{}{"id": "test", "price": inf.NewDec(12345, 2), "count": big.NewInt(42), "empty": (*inf.Dec)(nil)},
+			},
+			exactNumbers: true,
+			want: &Row{
+				Columns: []string{"id", "price", "count", "empty"},
+				Fields:  map[string]interface{}{"id": "test", "price": "123.45", "count": "42", "empty": nil},
 			},
 			wantErr: nil,
 		},
+		{
+			name: "row with collections",
+			input: &Row{
+				Columns: []string{"id", "tags", "samples", "ids", "empty"},
+				Fields: map[string]interface{}{
+					"id":      "test",
+					"tags":    map[string]string{"region": "eu", "sensor": "t1"},
+					"samples": []float64{0.1, 0.2},
+					"ids":     []gocql.UUID{{}},
+					"empty":   map[int]string(nil),
+				},
+			},
+			want: &Row{
+				Columns: []string{"id", "tags", "samples", "ids", "empty"},
+				Fields: map[string]interface{}{
+					"id":      "test",
+					"tags":    `{"region":"eu","sensor":"t1"}`,
+					"samples": "[0.1,0.2]",
+					"ids":     `["00000000-0000-0000-0000-000000000000"]`,
+					"empty":   nil,
+				},
+				Collections: map[string]interface{}{
+					"tags":    map[string]interface{}{"region": "eu", "sensor": "t1"},
+					"samples": []interface{}{0.1, 0.2},
+					"ids":     []interface{}{"00000000-0000-0000-0000-000000000000"},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "row with unsupported collection elements",
+			input: &Row{
+				Columns: []string{"id", "list"},
+				Fields:  map[string]interface{}{"id": "test", "list": []struct{}{{}}},
+			},
+			want: &Row{
+				Columns: []string{"id", "list"},
+				Fields:  map[string]interface{}{"id": "test", "list": []struct{}{{}}},
+			},
+			wantErr: fmt.Errorf("field list: list element: unsupported type struct {}"),
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.input.normalize()
+			err := tc.input.normalize(tc.exactNumbers)
 			if tc.wantErr == nil {
 				assert.NoError(t, err)
 			} else {
@@ -134,3 +216,72 @@ func TestRow_normalize(t *testing.T) {
 		})
 	}
 }
+
+func Test_fieldType(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input gocql.TypeInfo
+		want  data.FieldType
+	}{
+		{
+			name:  "text",
+			input: gocql.NewNativeType(4, gocql.TypeText, ""),
+			want:  data.FieldTypeString,
+		},
+		{
+			name:  "uuid",
+			input: gocql.NewNativeType(4, gocql.TypeUUID, ""),
+			want:  data.FieldTypeString,
+		},
+		{
+			name:  "int",
+			input: gocql.NewNativeType(4, gocql.TypeInt, ""),
+			want:  data.FieldTypeInt64,
+		},
+		{
+			name:  "double",
+			input: gocql.NewNativeType(4, gocql.TypeDouble, ""),
+			want:  data.FieldTypeFloat64,
+		},
+		{
+			name:  "timestamp",
+			input: gocql.NewNativeType(4, gocql.TypeTimestamp, ""),
+			want:  data.FieldTypeTime,
+		},
+		{
+			name:  "list",
+			input: gocql.CollectionType{NativeType: gocql.NewNativeType(4, gocql.TypeList, ""), Elem: gocql.NewNativeType(4, gocql.TypeInt, "")},
+			want:  data.FieldTypeString,
+		},
+		{
+			name:  "decimal",
+			input: gocql.NewNativeType(4, gocql.TypeDecimal, ""),
+			want:  data.FieldTypeFloat64,
+		},
+		{
+			name:  "duration",
+			input: gocql.NewNativeType(4, gocql.TypeDuration, ""),
+			want:  data.FieldTypeInt64,
+		},
+		{
+			name:  "unsupported",
+			input: gocql.NewNativeType(4, gocql.TypeCustom, ""),
+			want:  data.FieldTypeUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, fieldType(tc.input, false))
+		})
+	}
+}
+
+func TestRow_Size(t *testing.T) {
+	row := &Row{
+		Columns: []string{"id", "value", "time", "flag", "empty"},
+		Fields:  map[string]interface{}{"id": "sensor", "value": 0.1, "time": time.UnixMilli(1257894000000).UTC(), "flag": true, "empty": nil},
+	}
+
+	assert.Equal(t, 23, row.Size())
+}
@@ -3,7 +3,10 @@ package cassandra
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,11 +23,105 @@ type Settings struct {
 	Timeout               *int
 	TLSConfig             *tls.Config
 	AllowedAuthenticators []string
+	// ExactNumbers makes decimal and varint values to be returned as
+	// strings instead of float64 to keep their precision.
+	ExactNumbers bool
+	// Limits are default select options used when a query doesn't override them.
+	Limits SelectOptions
+}
+
+// SelectOptions controls paging and limits of a select query. Zero values
+// mean that session defaults are used, zero limits mean no limit at all.
+type SelectOptions struct {
+	// PageSize is a number of rows fetched from cluster in a single page.
+	PageSize int
+	// MaxRows is a maximum number of rows returned by the query.
+	MaxRows int
+	// MaxBytes is a maximum approximate size of values returned by the query.
+	MaxBytes int
+	// NoCache requests fresh data bypassing result caches in front of the session.
+	NoCache bool
+	// Split is a number of queries sharing the limits, e.g. per-ID queries of a single
+	// parallel query. Limits are divided evenly between them, so together they return
+	// no more than a single query.
+	Split int
+}
+
+// withDefaults returns options with zero values replaced by defaults.
+// Negative MaxRows and MaxBytes disable the limit regardless of the defaults.
+func (o SelectOptions) withDefaults(defaults SelectOptions) SelectOptions {
+	if o.PageSize == 0 {
+		o.PageSize = defaults.PageSize
+	}
+	if o.MaxRows == 0 {
+		o.MaxRows = defaults.MaxRows
+	}
+	if o.MaxBytes == 0 {
+		o.MaxBytes = defaults.MaxBytes
+	}
+
+	return o
+}
+
+// forPage returns options of a single page query. The page size is reduced to the
+// rows limit, so the page is not truncated, the rest of it couldn't be fetched later.
+func (o SelectOptions) forPage() SelectOptions {
+	if o.MaxRows > 0 && (o.PageSize <= 0 || o.PageSize > o.MaxRows) {
+		o.PageSize = o.MaxRows
+	}
+
+	return o
+}
+
+// Result contains rows returned by a select query grouped by ID.
+type Result struct {
+	Rows map[string][]Row
+	// IDs keeps the order in which IDs first appeared in the result.
+	IDs []string
+	// Notices contains warnings about the query processing, e.g.
+	// that result has been truncated because of the query limits.
+	Notices []string
+}
+
+// Add appends the row to the rows of the ID.
+func (r *Result) Add(id string, row Row) {
+	if r.Rows == nil {
+		r.Rows = make(map[string][]Row)
+	}
+	if _, ok := r.Rows[id]; !ok {
+		r.IDs = append(r.IDs, id)
+	}
+	r.Rows[id] = append(r.Rows[id], row)
+}
+
+// OrderedIDs returns IDs of the result in the order of their first appearance.
+// IDs which rows have been set directly, without Add, follow them in sorted order.
+func (r *Result) OrderedIDs() []string {
+	ids := make([]string, 0, len(r.Rows))
+	seen := make(map[string]bool, len(r.Rows))
+	for _, id := range r.IDs {
+		if _, ok := r.Rows[id]; ok && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+
+	var rest []string
+	for id := range r.Rows {
+		if !seen[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(ids, rest...)
 }
 
 // Session is a convenience wrapper for the gocql.Session.
 type Session struct {
-	session *gocql.Session
+	session      *gocql.Session
+	exactNumbers bool
+	limits       SelectOptions
 }
 
 // New creates a new cassandra cluster session using provided settings.
@@ -62,50 +159,152 @@ func New(cfg Settings) (*Session, error) {
 		return nil, fmt.Errorf("cluster.CreateSession: %w", err)
 	}
 
-	return &Session{clusterSession}, nil
+	return &Session{session: clusterSession, exactNumbers: cfg.ExactNumbers, limits: cfg.Limits}, nil
 }
 
+// RowFunc processes a single row of a select query result. Row ID is
+// a string presentation of the first column value.
+type RowFunc func(id string, row Row) error
+
 // Select queries the database with provided query string and returns result rows grouped by ID.
 // ID must be a first requested column in query and must be convertable to a string.
-func (s *Session) Select(ctx context.Context, query string, values ...interface{}) (rows map[string][]Row, err error) {
+// When the query limits are reached the iteration stops and the rows fetched so far
+// are returned along with a notice.
+func (s *Session) Select(ctx context.Context, opts SelectOptions, query string, values ...interface{}) (*Result, error) {
+	result := &Result{Rows: make(map[string][]Row)}
+	notices, err := s.SelectFunc(ctx, opts, func(id string, row Row) error {
+		result.Add(id, row)
+		return nil
+	}, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	result.Notices = notices
+
+	return result, nil
+}
+
+// SelectFunc queries the database with provided query string and calls fn for every
+// returned row as soon as it is fetched, so rows are not kept in memory. Iteration
+// stops on the first fn error. Notices about the query processing are returned.
+func (s *Session) SelectFunc(ctx context.Context, opts SelectOptions, fn RowFunc, query string, values ...interface{}) ([]string, error) {
+	notices, _, err := s.selectFunc(ctx, opts, false, nil, fn, query, values...)
+	return notices, err
+}
+
+// SelectPageFunc is the same as SelectFunc, but only a single page of rows starting
+// from pageState is fetched. Nil pageState means the first page. Paging state of the
+// next page is returned, it is empty when there are no more rows or the page is
+// truncated by the bytes limit. The page size is never larger than the rows limit.
+func (s *Session) SelectPageFunc(ctx context.Context, opts SelectOptions, pageState []byte, fn RowFunc, query string, values ...interface{}) ([]string, []byte, error) {
+	return s.selectFunc(ctx, opts, true, pageState, fn, query, values...)
+}
+
+func (s *Session) selectFunc(ctx context.Context, opts SelectOptions, paged bool, pageState []byte, fn RowFunc, query string, values ...interface{}) (notices []string, nextPageState []byte, err error) {
 	if !isSelect(query) {
-		return nil, fmt.Errorf("query is not a SELECT statement: %s", query)
+		return nil, nil, fmt.Errorf("query is not a SELECT statement: %s", query)
 	}
 
-	iter := s.session.Query(query, values...).WithContext(ctx).Iter()
+	opts = opts.withDefaults(s.limits)
+	if paged {
+		opts = opts.forPage()
+	}
+	q := s.session.Query(query, values...).WithContext(ctx)
+	if opts.PageSize > 0 {
+		q = q.PageSize(opts.PageSize)
+	}
+	if paged {
+		// disables fetching of the next pages
+		q = q.PageState(pageState)
+	}
+
+	iter := q.Iter()
 	defer func() {
 		if iterErr := iter.Close(); iterErr != nil {
 			err = fmt.Errorf("select query processing: %w", iterErr)
 		}
 	}()
+	if paged {
+		nextPageState = iter.PageState()
+	}
+
+	columns := iter.Columns()
+	names, types, units := flattenColumns(columns, s.exactNumbers)
 
-	rows = make(map[string][]Row)
+	limit := &rowLimit{maxRows: opts.MaxRows, maxBytes: opts.MaxBytes, split: opts.Split}
 	for {
-		rowValues := make(map[string]interface{}, len(iter.Columns()))
-		if !iter.MapScan(rowValues) {
+		dest, err := newScanDest(columns)
+		if err != nil {
+			return nil, nil, fmt.Errorf("newScanDest: %w", err)
+		}
+		if !iter.Scan(dest...) {
 			break
 		}
+		rowValues := flattenValues(columns, makeRowValues(columns, dest))
 
 		// first field is considered an id and used to distinguish different timeseries,
 		// so it must have string type. We are trying to convert id field value to
 		// a string or exit early in case when such conversion is not supported.
-		idFieldName := iter.Columns()[0].Name
-		id, err := toString(rowValues[idFieldName])
+		id, err := toString(rowValues[names[0]])
 		if err != nil {
-			return nil, fmt.Errorf("row processing: %w", err)
+			return nil, nil, fmt.Errorf("row processing: %w", err)
 		}
 
 		row := Row{
-			Columns: columnNames(iter.Columns()),
+			Columns: names,
 			Fields:  rowValues,
+			Types:   types,
+			Units:   units,
 		}
-		if err := row.normalize(); err != nil {
-			return nil, fmt.Errorf("row.normalize: %w", err)
+		if err := row.normalize(s.exactNumbers); err != nil {
+			return nil, nil, fmt.Errorf("row.normalize: %w", err)
 		}
-		rows[id] = append(rows[id], row)
+
+		if notice := limit.add(row); notice != "" {
+			notices = append(notices, notice)
+			// the next page would skip the rest of the truncated page
+			nextPageState = nil
+			break
+		}
+		if err := fn(id, row); err != nil {
+			return nil, nil, fmt.Errorf("row processing: %w", err)
+		}
+	}
+
+	return notices, nextPageState, nil
+}
+
+// rowLimit counts rows and bytes returned by a query. Limits which are not positive are disabled.
+// The query gets its share of the limits when they are split between several queries.
+type rowLimit struct {
+	maxRows, maxBytes int
+	split             int
+	rows, bytes       int
+}
+
+// add counts the row unless it exceeds a limit, in which case a notice about
+// the truncated result is returned and the row must not be returned.
+func (l *rowLimit) add(row Row) string {
+	if l.maxRows > 0 && l.rows >= l.share(l.maxRows) {
+		return fmt.Sprintf("Result is truncated: limit of %d rows reached", l.maxRows)
+	}
+	size := row.Size()
+	if l.maxBytes > 0 && l.bytes+size > l.share(l.maxBytes) {
+		return fmt.Sprintf("Result is truncated: limit of %d bytes reached", l.maxBytes)
+	}
+	l.rows++
+	l.bytes += size
+
+	return ""
+}
+
+// share returns the part of the limit available to the query, at least 1.
+func (l *rowLimit) share(limit int) int {
+	if l.split <= 1 {
+		return limit
 	}
 
-	return rows, nil
+	return max(limit/l.split, 1)
 }
 
 // GetKeyspaces queries the cassandra cluster for a list of existing keyspaces.
@@ -166,6 +365,58 @@ func (s *Session) GetColumns(keyspace, table, needType string) ([]string, error)
 	return columns, nil
 }
 
+// ColumnType describes CQL type of a table column.
+type ColumnType struct {
+	// Type is a CQL type name, e.g. int, text or list.
+	Type string
+	// Elem is a type name of collection elements, for maps it's a type of values.
+	Elem string
+}
+
+// IsCollection reports whether the column is a list, set or map.
+func (c ColumnType) IsCollection() bool {
+	return c.Elem != ""
+}
+
+// GetColumnTypes queries the cassandra cluster for types of all columns of a given keyspace, table.
+func (s *Session) GetColumnTypes(keyspace, table string) (map[string]ColumnType, error) {
+	keyspaceMetadata, err := s.session.KeyspaceMetadata(keyspace)
+	if err != nil {
+		return nil, fmt.Errorf("session.KeyspaceMetadata: %w", err)
+	}
+
+	tableMetadata, ok := keyspaceMetadata.Tables[table]
+	if !ok {
+		return nil, fmt.Errorf("no such table: '%s'", table)
+	}
+
+	types := make(map[string]ColumnType, len(tableMetadata.Columns))
+	for name, column := range tableMetadata.Columns {
+		columnType := ColumnType{Type: column.Type.Type().String()}
+		if collection, ok := column.Type.(gocql.CollectionType); ok && collection.Elem != nil {
+			columnType.Elem = collection.Elem.Type().String()
+		}
+		types[name] = columnType
+	}
+
+	return types, nil
+}
+
+// GetTableComment queries the cassandra cluster for a comment of the table.
+func (s *Session) GetTableComment(ctx context.Context, keyspace, table string) (string, error) {
+	var comment string
+	statement := "SELECT comment FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?"
+	err := s.session.Query(statement, keyspace, table).WithContext(ctx).Scan(&comment)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return "", fmt.Errorf("no such table: '%s'", table)
+	}
+	if err != nil {
+		return "", fmt.Errorf("session.Query: %w", err)
+	}
+
+	return comment, nil
+}
+
 // Ping executes a simple query to check the connection status.
 func (s *Session) Ping(ctx context.Context) error {
 	err := s.session.Query("SELECT key FROM system.local").WithContext(ctx).Exec()
@@ -176,6 +427,17 @@ func (s *Session) Ping(ctx context.Context) error {
 	return nil
 }
 
+// GetVersion queries the release version of the cassandra node the session is connected to.
+func (s *Session) GetVersion(ctx context.Context) (string, error) {
+	var version string
+	err := s.session.Query("SELECT release_version FROM system.local").WithContext(ctx).Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("session.Query: %w", err)
+	}
+
+	return version, nil
+}
+
 // Close closes connections to cluster.
 func (s *Session) Close() {
 	s.session.Close()
@@ -212,11 +474,78 @@ func toString(val interface{}) (string, error) {
 	return str, nil
 }
 
-func columnNames(columnInfo []gocql.ColumnInfo) []string {
-	names := make([]string, 0, len(columnInfo))
+// newScanDest creates scan destinations for a single row. Every destination
+// is a pointer to a pointer, so gocql sets it to nil when the value is NULL.
+// Tuple columns are expanded to one destination per tuple element as
+// required by gocql.Iter.Scan.
+func newScanDest(columnInfo []gocql.ColumnInfo) ([]interface{}, error) {
+	dest := make([]interface{}, 0, len(columnInfo))
+	for _, col := range columnInfo {
+		elems := []gocql.TypeInfo{col.TypeInfo}
+		if tuple, ok := col.TypeInfo.(gocql.TupleTypeInfo); ok {
+			elems = tuple.Elems
+		}
+		for _, elem := range elems {
+			val, err := newScanValue(elem)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			dest = append(dest, val)
+		}
+	}
+
+	return dest, nil
+}
+
+// newScanValue creates a pointer to pointer scan destination of the type.
+// UDTs are scanned to udtValue, so their NULL members are nil as well.
+func newScanValue(info gocql.TypeInfo) (interface{}, error) {
+	if _, ok := info.(gocql.UDTTypeInfo); ok {
+		return new(*udtValue), nil
+	}
+
+	val, err := info.NewWithError()
+	if err != nil {
+		return nil, err
+	}
+
+	return reflect.New(reflect.TypeOf(val)).Interface(), nil
+}
+
+// makeRowValues dereferences scanned values and maps them to column names.
+// NULL values are represented by nil, tuple elements are collected to a slice.
+func makeRowValues(columnInfo []gocql.ColumnInfo, dest []interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(columnInfo))
+	i := 0
 	for _, col := range columnInfo {
-		names = append(names, col.Name)
+		tuple, ok := col.TypeInfo.(gocql.TupleTypeInfo)
+		if !ok {
+			values[col.Name] = dereference(dest[i])
+			i++
+			continue
+		}
+
+		elems := make([]interface{}, 0, len(tuple.Elems))
+		for range tuple.Elems {
+			elems = append(elems, dereference(dest[i]))
+			i++
+		}
+		values[col.Name] = elems
+	}
+
+	return values
+}
+
+// dereference returns a value referenced by a pointer to pointer
+// scan destination or nil in case when the value is NULL.
+func dereference(dest interface{}) interface{} {
+	ptr := reflect.ValueOf(dest).Elem()
+	if ptr.IsNil() {
+		return nil
+	}
+	if udt, ok := ptr.Interface().(*udtValue); ok {
+		return map[string]interface{}(*udt)
 	}
 
-	return names
+	return ptr.Elem().Interface()
 }
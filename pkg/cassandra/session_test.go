@@ -126,3 +126,210 @@ func Test_isSelect(t *testing.T) {
 		})
 	}
 }
+
+func Test_makeRowValues(t *testing.T) {
+	columns := []gocql.ColumnInfo{
+		{Name: "id", TypeInfo: gocql.NewNativeType(4, gocql.TypeText, "")},
+		{Name: "value", TypeInfo: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+		{Name: "pair", TypeInfo: gocql.TupleTypeInfo{
+			NativeType: gocql.NewNativeType(4, gocql.TypeTuple, ""),
+			Elems: []gocql.TypeInfo{
+				gocql.NewNativeType(4, gocql.TypeInt, ""),
+				gocql.NewNativeType(4, gocql.TypeText, ""),
+			},
+		}},
+	}
+
+	dest, err := newScanDest(columns)
+	assert.NoError(t, err)
+	assert.Len(t, dest, 4)
+
+	id, num := "1", 2
+	*dest[0].(**string) = &id
+	*dest[2].(**int) = &num
+
+	want := map[string]interface{}{
+		"id":    "1",
+		"value": nil,
+		"pair":  []interface{}{2, nil},
+	}
+	assert.Equal(t, want, makeRowValues(columns, dest))
+}
+
+func Test_makeRowValuesUDT(t *testing.T) {
+	columns := testColumns()[1:2]
+
+	// value is 2, unit is NULL, location is a UDT with NULL lon
+	udt := []byte{
+		0, 0, 0, 4, 0, 0, 0, 2,
+		0xff, 0xff, 0xff, 0xff,
+		0, 0, 0, 16, 0, 0, 0, 8, 0x40, 0x49, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff,
+	}
+
+	dest, err := newScanDest(columns)
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(columns[0].TypeInfo, udt, dest[0]))
+
+	want := map[string]interface{}{
+		"m": map[string]interface{}{
+			"value":    2,
+			"unit":     nil,
+			"location": map[string]interface{}{"lat": 50.0, "lon": nil},
+		},
+	}
+	assert.Equal(t, want, makeRowValues(columns, dest))
+
+	assert.NoError(t, gocql.Unmarshal(columns[0].TypeInfo, nil, dest[0]))
+	assert.Equal(t, map[string]interface{}{"m": nil}, makeRowValues(columns, dest))
+}
+
+func TestSelectOptions_withDefaults(t *testing.T) {
+	defaults := SelectOptions{PageSize: 1000, MaxRows: 100000, MaxBytes: 1 << 20}
+
+	testCases := []struct {
+		name  string
+		input SelectOptions
+		want  SelectOptions
+	}{
+		{
+			name:  "empty",
+			input: SelectOptions{},
+			want:  defaults,
+		},
+		{
+			name:  "override",
+			input: SelectOptions{PageSize: 10, MaxRows: 20},
+			want:  SelectOptions{PageSize: 10, MaxRows: 20, MaxBytes: 1 << 20},
+		},
+		{
+			name:  "unlimited",
+			input: SelectOptions{MaxRows: -1, MaxBytes: -1},
+			want:  SelectOptions{PageSize: 1000, MaxRows: -1, MaxBytes: -1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.input.withDefaults(defaults))
+		})
+	}
+}
+
+func TestSelectOptions_forPage(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input SelectOptions
+		want  SelectOptions
+	}{
+		{
+			name:  "no rows limit",
+			input: SelectOptions{PageSize: 1000, MaxBytes: 100},
+			want:  SelectOptions{PageSize: 1000, MaxBytes: 100},
+		},
+		{
+			name:  "page within limit",
+			input: SelectOptions{PageSize: 100, MaxRows: 1000},
+			want:  SelectOptions{PageSize: 100, MaxRows: 1000},
+		},
+		{
+			name:  "page over limit",
+			input: SelectOptions{PageSize: 1000, MaxRows: 100},
+			want:  SelectOptions{PageSize: 100, MaxRows: 100},
+		},
+		{
+			name:  "driver page size",
+			input: SelectOptions{MaxRows: 100},
+			want:  SelectOptions{PageSize: 100, MaxRows: 100},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.input.forPage())
+		})
+	}
+}
+
+func Test_rowLimit(t *testing.T) {
+	row := Row{Columns: []string{"id", "value"}, Fields: map[string]interface{}{"id": "abc", "value": 1.5}}
+
+	testCases := []struct {
+		name       string
+		limit      rowLimit
+		wantRows   int
+		wantNotice string
+	}{
+		{
+			name:     "no limits",
+			limit:    rowLimit{},
+			wantRows: 10,
+		},
+		{
+			name:     "disabled limits",
+			limit:    rowLimit{maxRows: -1, maxBytes: -1},
+			wantRows: 10,
+		},
+		{
+			name:       "rows",
+			limit:      rowLimit{maxRows: 3},
+			wantRows:   3,
+			wantNotice: "Result is truncated: limit of 3 rows reached",
+		},
+		{
+			name:       "bytes",
+			limit:      rowLimit{maxBytes: 25},
+			wantRows:   2,
+			wantNotice: "Result is truncated: limit of 25 bytes reached",
+		},
+		{
+			name:       "rows before bytes",
+			limit:      rowLimit{maxRows: 2, maxBytes: 22},
+			wantRows:   2,
+			wantNotice: "Result is truncated: limit of 2 rows reached",
+		},
+		{
+			name:       "split",
+			limit:      rowLimit{maxRows: 10, maxBytes: 110, split: 4},
+			wantRows:   2,
+			wantNotice: "Result is truncated: limit of 10 rows reached",
+		},
+		{
+			name:       "split below one row",
+			limit:      rowLimit{maxRows: 2, split: 4},
+			wantRows:   1,
+			wantNotice: "Result is truncated: limit of 2 rows reached",
+		},
+		{
+			name:     "exact bytes",
+			limit:    rowLimit{maxRows: 10, maxBytes: 110},
+			wantRows: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rows int
+			var notice string
+			for range 10 {
+				if notice = tc.limit.add(row); notice != "" {
+					break
+				}
+				rows++
+			}
+			assert.Equal(t, tc.wantRows, rows)
+			assert.Equal(t, tc.wantNotice, notice)
+		})
+	}
+}
+
+func TestResult_OrderedIDs(t *testing.T) {
+	result := &Result{}
+	result.Add("b", Row{})
+	result.Add("a", Row{})
+	result.Add("b", Row{})
+	result.Rows["d"] = []Row{{}}
+	result.Rows["c"] = []Row{{}}
+
+	assert.Equal(t, []string{"b", "a", "c", "d"}, result.OrderedIDs())
+	assert.Len(t, result.Rows["b"], 2)
+}
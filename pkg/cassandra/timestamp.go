@@ -0,0 +1,23 @@
+package cassandra
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Timestamp is a time value of a bound query parameter. It's marshalled
+// according to the type of the column it's compared to, so the same value
+// could be used for timestamp, date and bigint (milliseconds since epoch) columns.
+// Time range bounds of the queries are bound as Timestamp, unlike other time values.
+type Timestamp time.Time
+
+// MarshalCQL implements gocql.Marshaler interface.
+func (t Timestamp) MarshalCQL(info gocql.TypeInfo) ([]byte, error) {
+	switch info.Type() {
+	case gocql.TypeBigInt, gocql.TypeInt, gocql.TypeVarint, gocql.TypeCounter:
+		return gocql.Marshal(info, time.Time(t).UnixMilli())
+	default:
+		return gocql.Marshal(info, time.Time(t))
+	}
+}
@@ -0,0 +1,35 @@
+package cassandra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamp_MarshalCQL(t *testing.T) {
+	ts := time.UnixMilli(1257894000000).UTC()
+
+	testCases := []struct {
+		name string
+		typ  gocql.Type
+		want interface{}
+	}{
+		{name: "timestamp", typ: gocql.TypeTimestamp, want: ts},
+		{name: "bigint", typ: gocql.TypeBigInt, want: ts.UnixMilli()},
+		{name: "date", typ: gocql.TypeDate, want: ts},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := gocql.NewNativeType(4, tc.typ, "")
+			got, err := Timestamp(ts).MarshalCQL(info)
+			assert.NoError(t, err)
+
+			want, err := gocql.Marshal(info, tc.want)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
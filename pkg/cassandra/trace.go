@@ -0,0 +1,91 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+)
+
+// Span is a row of the traces table of the Jaeger Cassandra schema.
+// Times and durations are in microseconds.
+type Span struct {
+	TraceID       []byte
+	SpanID        int64
+	ParentID      int64
+	OperationName string
+	Flags         int
+	StartTime     int64
+	Duration      int64
+	Tags          []KeyValue
+	Logs          []SpanLog
+	Refs          []SpanRef
+	Process       Process
+}
+
+// KeyValue is a value of the keyvalue type of the Jaeger schema,
+// ValueType tells which of the value fields is set.
+type KeyValue struct {
+	Key         string  `cql:"key"`
+	ValueType   string  `cql:"value_type"`
+	ValueString string  `cql:"value_string"`
+	ValueBool   bool    `cql:"value_bool"`
+	ValueLong   int64   `cql:"value_long"`
+	ValueDouble float64 `cql:"value_double"`
+	ValueBinary []byte  `cql:"value_binary"`
+}
+
+// Value returns the value of the ValueType field.
+func (kv KeyValue) Value() interface{} {
+	switch kv.ValueType {
+	case "bool":
+		return kv.ValueBool
+	case "int64":
+		return kv.ValueLong
+	case "float64":
+		return kv.ValueDouble
+	case "binary":
+		return fmt.Sprintf("%x", kv.ValueBinary)
+	default:
+		return kv.ValueString
+	}
+}
+
+// SpanLog is a value of the log type of the Jaeger schema.
+type SpanLog struct {
+	Timestamp int64      `cql:"ts"`
+	Fields    []KeyValue `cql:"fields"`
+}
+
+// SpanRef is a value of the span_ref type of the Jaeger schema.
+type SpanRef struct {
+	RefType string `cql:"ref_type"`
+	TraceID []byte `cql:"trace_id"`
+	SpanID  int64  `cql:"span_id"`
+}
+
+// Process is a value of the process type of the Jaeger schema.
+type Process struct {
+	ServiceName string     `cql:"service_name"`
+	Tags        []KeyValue `cql:"tags"`
+}
+
+// GetTraceSpans queries spans of the trace from the table of Jaeger Cassandra schema.
+func (s *Session) GetTraceSpans(ctx context.Context, keyspace, table string, traceID []byte) ([]Span, error) {
+	statement := fmt.Sprintf("SELECT trace_id, span_id, parent_id, operation_name, flags, start_time, duration, tags, logs, refs, process FROM %s.%s WHERE trace_id = ?", keyspace, table)
+	scanner := s.session.Query(statement, traceID).WithContext(ctx).Iter().Scanner()
+
+	var spans []Span
+	for scanner.Next() {
+		var span Span
+		err := scanner.Scan(&span.TraceID, &span.SpanID, &span.ParentID, &span.OperationName, &span.Flags,
+			&span.StartTime, &span.Duration, &span.Tags, &span.Logs, &span.Refs, &span.Process)
+		if err != nil {
+			return nil, fmt.Errorf("scanner.Scan: %w", err)
+		}
+		spans = append(spans, span)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("query processing: %w", err)
+	}
+
+	return spans, nil
+}
@@ -3,23 +3,18 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"time"
 
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/plugin"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
-var (
-	timeFromRegexp      = regexp.MustCompile(`\$__timeFrom`)
-	timeToRegexp        = regexp.MustCompile(`\$__timeTo`)
-	unixEpochFromRegexp = regexp.MustCompile(`\$__unixEpochFrom`)
-	unixEpochToRegexp   = regexp.MustCompile(`\$__unixEpochTo`)
-)
-
 const (
 	queryTypeQuery = "query"
 	queryTypeAlert = "alert"
+	// queryTypeAnnotation is a raw query of annotation events.
+	queryTypeAnnotation = "annotation"
+	// queryTypeTrace reads spans of a trace stored with the Jaeger schema.
+	queryTypeTrace = "trace"
 )
 
 type dataQuery struct {
@@ -28,16 +23,51 @@ type dataQuery struct {
 	RawQuery     bool   `json:"rawQuery"`
 	RefID        string `json:"refId"`
 	Target       string `json:"target"`
+	// BindValues are template variables values referenced by $__bind(N) macros,
+	// a multi-value variable is a list of strings.
+	BindValues []interface{} `json:"bindValues,omitempty"`
+
+	ColumnTime        string `json:"columnTime"`
+	ColumnValue       string `json:"columnValue"`
+	Keyspace          string `json:"keyspace"`
+	Table             string `json:"table"`
+	ColumnID          string `json:"columnId"`
+	ValueID           string `json:"valueId"`
+	Alias             string `json:"alias,omitempty"`
+	AllowFiltering    bool   `json:"filtering,omitempty"`
+	Instant           bool   `json:"instant,omitempty"`
+	ExpandCollections bool   `json:"expandCollections,omitempty"`
+	PageSize          int    `json:"pageSize,omitempty"`
+	MaxRows           int    `json:"maxRows,omitempty"`
+	MaxBytes          int    `json:"maxBytes,omitempty"`
+	Aggregation       string `json:"aggregation,omitempty"`
+	BucketColumn      string `json:"bucketColumn,omitempty"`
+	BucketSize        string `json:"bucketSize,omitempty"`
+	BucketFormat      string `json:"bucketFormat,omitempty"`
+	Parallel          bool   `json:"parallel,omitempty"`
+	NoCache           bool   `json:"noCache,omitempty"`
+	Incremental       bool   `json:"incremental,omitempty"`
+	Format            string `json:"format,omitempty"`
+	LogBodyColumn     string `json:"logBodyColumn,omitempty"`
+	LogLevelColumn    string `json:"logLevelColumn,omitempty"`
+	PageState         string `json:"pageState,omitempty"`
+	TraceID           string `json:"traceId,omitempty"`
+	SortBy            string `json:"sortBy,omitempty"`
+	Reduce            string `json:"reduce,omitempty"`
+
+	ValueColumns []dataValueColumn `json:"valueColumns,omitempty"`
+	Filters      []dataFilter      `json:"filters,omitempty"`
+}
+
+type dataValueColumn struct {
+	Column string `json:"column"`
+	Alias  string `json:"alias,omitempty"`
+}
 
-	ColumnTime     string `json:"columnTime"`
-	ColumnValue    string `json:"columnValue"`
-	Keyspace       string `json:"keyspace"`
-	Table          string `json:"table"`
-	ColumnID       string `json:"columnId"`
-	ValueID        string `json:"valueId"`
-	Alias          string `json:"alias,omitempty"`
-	AllowFiltering bool   `json:"filtering,omitempty"`
-	Instant        bool   `json:"instant,omitempty"`
+type dataFilter struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
 }
 
 // parseDataQuery is a simple helper to unmarshal
@@ -49,34 +79,68 @@ func parseDataQuery(q *backend.DataQuery) (*plugin.Query, error) {
 		return nil, fmt.Errorf("json.Unmarshal: %w", err)
 	}
 
-	if dq.RawQuery {
-		dq.applyTimeRange(q.TimeRange.From, q.TimeRange.To)
+	var values []interface{}
+	if dq.RawQuery || dq.QueryType == queryTypeAnnotation {
+		dq.Target, values, err = compileMacros(dq.Target, macroContext{
+			from:       q.TimeRange.From,
+			to:         q.TimeRange.To,
+			interval:   q.Interval,
+			bindValues: dq.BindValues,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("compileMacros: %w", err)
+		}
 	}
 
-	return &plugin.Query{
-		RawQuery:       dq.RawQuery,
-		Target:         dq.Target,
-		Keyspace:       dq.Keyspace,
-		Table:          dq.Table,
-		ColumnValue:    dq.ColumnValue,
-		ColumnID:       dq.ColumnID,
-		ValueID:        dq.ValueID,
-		AliasID:        dq.Alias,
-		ColumnTime:     dq.ColumnTime,
-		TimeFrom:       q.TimeRange.From,
-		TimeTo:         q.TimeRange.To,
-		AllowFiltering: dq.AllowFiltering,
-		Instant:        dq.Instant,
-		IsAlertQuery:   dq.QueryType == queryTypeAlert,
-	}, nil
-}
+	var valueColumns []plugin.ValueColumn
+	for _, c := range dq.ValueColumns {
+		valueColumns = append(valueColumns, plugin.ValueColumn{Column: c.Column, Alias: c.Alias})
+	}
 
-func (dq *dataQuery) applyTimeRange(from time.Time, to time.Time) {
-	query := []byte(dq.Target)
-	query = timeFromRegexp.ReplaceAll(query, []byte(fmt.Sprintf("%d", from.UnixMilli())))
-	query = timeToRegexp.ReplaceAll(query, []byte(fmt.Sprintf("%d", to.UnixMilli())))
-	query = unixEpochFromRegexp.ReplaceAll(query, []byte(fmt.Sprintf("%d", from.Unix())))
-	query = unixEpochToRegexp.ReplaceAll(query, []byte(fmt.Sprintf("%d", to.Unix())))
+	var filters []plugin.Filter
+	for _, f := range dq.Filters {
+		filters = append(filters, plugin.Filter{Column: f.Column, Operator: f.Operator, Value: f.Value})
+	}
 
-	dq.Target = string(query)
+	return &plugin.Query{
+		RawQuery:          dq.RawQuery,
+		Target:            dq.Target,
+		Values:            values,
+		Keyspace:          dq.Keyspace,
+		Table:             dq.Table,
+		ColumnValue:       dq.ColumnValue,
+		ValueColumns:      valueColumns,
+		ColumnID:          dq.ColumnID,
+		ValueID:           dq.ValueID,
+		AliasID:           dq.Alias,
+		ColumnTime:        dq.ColumnTime,
+		TimeFrom:          q.TimeRange.From,
+		TimeTo:            q.TimeRange.To,
+		AllowFiltering:    dq.AllowFiltering,
+		Instant:           dq.Instant,
+		IsAlertQuery:      dq.QueryType == queryTypeAlert,
+		IsAnnotationQuery: dq.QueryType == queryTypeAnnotation,
+		IsTraceQuery:      dq.QueryType == queryTypeTrace,
+		ExpandCollections: dq.ExpandCollections,
+		PageSize:          dq.PageSize,
+		MaxRows:           dq.MaxRows,
+		MaxBytes:          dq.MaxBytes,
+		Aggregation:       dq.Aggregation,
+		Interval:          q.Interval,
+		MaxDataPoints:     q.MaxDataPoints,
+		Filters:           filters,
+		BucketColumn:      dq.BucketColumn,
+		BucketSize:        dq.BucketSize,
+		BucketFormat:      dq.BucketFormat,
+		Parallel:          dq.Parallel,
+		NoCache:           dq.NoCache,
+		Incremental:       dq.Incremental,
+		Format:            dq.Format,
+		LogBodyColumn:     dq.LogBodyColumn,
+		LogLevelColumn:    dq.LogLevelColumn,
+		PageState:         dq.PageState,
+		TraceID:           dq.TraceID,
+		SortBy:            dq.SortBy,
+		Reduce:            dq.Reduce,
+	}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/plugin"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/stretchr/testify/assert"
@@ -22,21 +23,46 @@ func Test_parseDataQuery(t *testing.T) {
 			jsonStr: []byte(`{"datasourceId": 1, "queryType": "query", "rawQuery": true, "refId": "123456789",
 							  "target": "SELECT * from Keyspace.Table", "columnTime": "Time", "columnValue": "Value",
 							  "keyspace": "Keyspace", "table": "Table", "columnId": "ID", "valueId": "123",
-							  "alias": "Alias", "filtering": true, "instant": true}`),
+							  "alias": "Alias", "filtering": true, "instant": true, "expandCollections": true,
+							  "pageSize": 100, "maxRows": 1000, "maxBytes": 100000, "aggregation": "avg",
+							  "valueColumns": [{"column": "Humidity", "alias": "humidity {{ ID }}"}],
+							  "filters": [{"column": "region", "operator": "IN", "value": "eu,us"}],
+							  "bucketColumn": "day", "bucketSize": "day", "bucketFormat": "YYYYMMDD",
+							  "parallel": true, "noCache": true, "incremental": true,
+							  "format": "table", "logBodyColumn": "message", "logLevelColumn": "level", "pageState": "AQID", "sortBy": "last", "reduce": "max"}`),
 			want: &plugin.Query{
-				RawQuery:       true,
-				Target:         "SELECT * from Keyspace.Table",
-				Keyspace:       "Keyspace",
-				Table:          "Table",
-				ColumnValue:    "Value",
-				ColumnID:       "ID",
-				ValueID:        "123",
-				AliasID:        "Alias",
-				ColumnTime:     "Time",
-				TimeFrom:       time.Unix(1257894000, 0),
-				TimeTo:         time.Unix(1257894010, 0),
-				AllowFiltering: true,
-				Instant:        true,
+				RawQuery:          true,
+				Target:            "SELECT * from Keyspace.Table",
+				Keyspace:          "Keyspace",
+				Table:             "Table",
+				ColumnValue:       "Value",
+				ValueColumns:      []plugin.ValueColumn{{Column: "Humidity", Alias: "humidity {{ ID }}"}},
+				ColumnID:          "ID",
+				ValueID:           "123",
+				AliasID:           "Alias",
+				ColumnTime:        "Time",
+				TimeFrom:          time.Unix(1257894000, 0),
+				TimeTo:            time.Unix(1257894010, 0),
+				AllowFiltering:    true,
+				Instant:           true,
+				ExpandCollections: true,
+				PageSize:          100,
+				MaxRows:           1000,
+				MaxBytes:          100000,
+				Aggregation:       "avg",
+				Filters:           []plugin.Filter{{Column: "region", Operator: "IN", Value: "eu,us"}},
+				BucketColumn:      "day",
+				BucketSize:        "day",
+				BucketFormat:      "YYYYMMDD",
+				Parallel:          true,
+				NoCache:           true,
+				Incremental:       true,
+				Format:            "table",
+				LogBodyColumn:     "message",
+				LogLevelColumn:    "level",
+				PageState:         "AQID",
+				SortBy:            "last",
+				Reduce:            "max",
 			},
 		},
 		{
@@ -84,6 +110,31 @@ func Test_parseDataQuery(t *testing.T) {
 				IsAlertQuery:   true,
 			},
 		},
+		{
+			name:      "trace query",
+			timeRange: backend.TimeRange{From: time.Unix(1257894000, 0), To: time.Unix(1257894010, 0)},
+			jsonStr:   []byte(`{"datasourceId": 1, "queryType": "trace", "refId": "A", "keyspace": "jaeger_v1_dc1", "traceId": "6b7c9a1e2f3d4c5b"}`),
+			want: &plugin.Query{
+				Keyspace:     "jaeger_v1_dc1",
+				TimeFrom:     time.Unix(1257894000, 0),
+				TimeTo:       time.Unix(1257894010, 0),
+				IsTraceQuery: true,
+				TraceID:      "6b7c9a1e2f3d4c5b",
+			},
+		},
+		{
+			name:      "annotation query",
+			timeRange: backend.TimeRange{From: time.Unix(1257894000, 0).UTC(), To: time.Unix(1257894010, 0).UTC()},
+			jsonStr: []byte(`{"datasourceId": 1, "queryType": "annotation", "refId": "Anno",
+							  "target": "SELECT time, title FROM ks.events WHERE $__timeFilter(time)"}`),
+			want: &plugin.Query{
+				Target:            "SELECT time, title FROM ks.events WHERE time >= ? AND time <= ?",
+				Values:            []interface{}{cassandra.Timestamp(time.Unix(1257894000, 0).UTC()), cassandra.Timestamp(time.Unix(1257894010, 0).UTC())},
+				TimeFrom:          time.Unix(1257894000, 0).UTC(),
+				TimeTo:            time.Unix(1257894010, 0).UTC(),
+				IsAnnotationQuery: true,
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/plugin"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -21,6 +22,7 @@ type ds interface {
 	GetColumns(keyspace, table, needType string) ([]string, error)
 	GetVariables(ctx context.Context, query string) ([]plugin.Variable, error)
 	CheckHealth(ctx context.Context) error
+	QuerySlots() chan struct{}
 	Dispose()
 }
 
@@ -44,6 +46,8 @@ func New(fn datasource.InstanceFactoryFunc) datasource.ServeOpts {
 	queryTypeMux := datasource.NewQueryTypeMux()
 	queryTypeMux.HandleFunc("query", h.queryMetricData)
 	queryTypeMux.HandleFunc("alert", h.queryMetricData)
+	queryTypeMux.HandleFunc("annotation", h.queryMetricData)
+	queryTypeMux.HandleFunc("trace", h.queryMetricData)
 
 	return datasource.ServeOpts{
 		CheckHealthHandler:  h,
@@ -60,27 +64,60 @@ func (h *handler) queryMetricData(ctx context.Context, req *backend.QueryDataReq
 		return nil, fmt.Errorf("failed to get plugin instance: %w", err)
 	}
 
-	responses := backend.Responses{}
+	// queries are executed concurrently, an error of a query
+	// is reported in its response and doesn't affect the others.
+	// Query slots are shared with the other requests to the datasource.
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		responses = backend.Responses{}
+		slots     = p.QuerySlots()
+	)
 	for _, q := range req.Queries {
-		backend.Logger.Debug("Process metrics request", "Request", q.JSON)
-		cassQuery, err := parseDataQuery(&q)
-		if err != nil {
-			backend.Logger.Error("Failed to parse query", "Message", err)
-			responses[q.RefID] = backend.DataResponse{Error: fmt.Errorf("json.Unmarshal: %w", err)}
-			continue
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var response backend.DataResponse
+			select {
+			case slots <- struct{}{}:
+				// select chooses randomly when the request is cancelled and a slot is free at once
+				if ctx.Err() == nil {
+					response = execDataQuery(ctx, p, &q)
+				} else {
+					response = backend.DataResponse{Error: fmt.Errorf("query cancelled: %w", ctx.Err())}
+				}
+				<-slots
+			case <-ctx.Done():
+				response = backend.DataResponse{Error: fmt.Errorf("query cancelled: %w", ctx.Err())}
+			}
+
+			mu.Lock()
+			responses[q.RefID] = response
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return &backend.QueryDataResponse{Responses: responses}, nil
+}
 
-		dataFrames, err := p.ExecQuery(ctx, cassQuery)
-		if err != nil {
-			backend.Logger.Error("Failed to execute query", "Message", err)
-			responses[q.RefID] = backend.DataResponse{Error: fmt.Errorf("p.ExecQuery: %w", err)}
-			continue
-		}
+// execDataQuery parses and executes a single query of a data request.
+func execDataQuery(ctx context.Context, p ds, q *backend.DataQuery) backend.DataResponse {
+	backend.Logger.Debug("Process metrics request", "Request", q.JSON)
+	cassQuery, err := parseDataQuery(q)
+	if err != nil {
+		backend.Logger.Error("Failed to parse query", "Message", err)
+		return backend.DataResponse{Error: fmt.Errorf("parseDataQuery: %w", err)}
+	}
 
-		responses[q.RefID] = backend.DataResponse{Frames: dataFrames}
+	dataFrames, err := p.ExecQuery(ctx, cassQuery)
+	if err != nil {
+		backend.Logger.Error("Failed to execute query", "Message", err)
+		return backend.DataResponse{Error: fmt.Errorf("p.ExecQuery: %w", err)}
 	}
 
-	return &backend.QueryDataResponse{Responses: responses}, nil
+	return backend.DataResponse{Frames: dataFrames}
 }
 
 // getKeyspaces is a handle to fetch keyspaces list.
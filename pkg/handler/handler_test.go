@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -35,6 +37,9 @@ type pluginMock struct {
 	onGetVariables func(ctx context.Context, query string) ([]plugin.Variable, error)
 	onCheckHealth  func(ctx context.Context) error
 	onDispose      func()
+
+	slotsOnce sync.Once
+	slots     chan struct{}
 }
 
 func (p *pluginMock) ExecQuery(ctx context.Context, q *plugin.Query) (data.Frames, error) {
@@ -61,6 +66,11 @@ func (p *pluginMock) CheckHealth(ctx context.Context) error {
 	return p.onCheckHealth(ctx)
 }
 
+func (p *pluginMock) QuerySlots() chan struct{} {
+	p.slotsOnce.Do(func() { p.slots = make(chan struct{}, 2) })
+	return p.slots
+}
+
 func (p *pluginMock) Dispose() {}
 
 func Test_queryMetricData(t *testing.T) {
@@ -185,6 +195,86 @@ func Test_queryMetricData(t *testing.T) {
 	}
 }
 
+func Test_queryMetricData_concurrency(t *testing.T) {
+	var running, maxRunning int32
+	p := &pluginMock{
+		onExecQuery: func(_ context.Context, q *plugin.Query) (data.Frames, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			if q.ValueID == "3" {
+				return nil, errors.New("timeout")
+			}
+			return data.Frames{{Name: q.ValueID}}, nil
+		},
+	}
+
+	var queries []backend.DataQuery
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		queries = append(queries, backend.DataQuery{
+			RefID: id,
+			JSON:  []byte(`{"queryType": "query", "keyspace": "Keyspace", "table": "Table", "valueId": "` + id + `"}`),
+		})
+	}
+
+	// the limit is shared by concurrent requests
+	h := &handler{instanceManager: &instanceManagerMock{plugin: p}}
+	var wg sync.WaitGroup
+	results := make([]*backend.QueryDataResponse, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := h.queryMetricData(context.TODO(), &backend.QueryDataRequest{Queries: queries})
+			assert.NoError(t, err)
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxRunning, int32(2))
+	for _, result := range results {
+		assert.Len(t, result.Responses, 5)
+		assert.EqualError(t, result.Responses["3"].Error, "p.ExecQuery: timeout")
+		for _, id := range []string{"1", "2", "4", "5"} {
+			assert.NoError(t, result.Responses[id].Error)
+			assert.Equal(t, data.Frames{{Name: id}}, result.Responses[id].Frames)
+		}
+	}
+}
+
+func Test_queryMetricData_cancelled(t *testing.T) {
+	p := &pluginMock{
+		onExecQuery: func(ctx context.Context, q *plugin.Query) (data.Frames, error) {
+			t.Error("query of a cancelled request is executed")
+			return nil, ctx.Err()
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	h := &handler{instanceManager: &instanceManagerMock{plugin: p}}
+	result, err := h.queryMetricData(ctx, &backend.QueryDataRequest{Queries: []backend.DataQuery{
+		{RefID: "A", JSON: []byte(`{"queryType": "query"}`)},
+		{RefID: "B", JSON: []byte(`{"queryType": "query"}`)},
+		{RefID: "C", JSON: []byte(`{"queryType": "query"}`)},
+	}})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Responses, 3)
+	for _, response := range result.Responses {
+		assert.ErrorIs(t, response.Error, context.Canceled)
+	}
+}
+
 func Test_CheckHealth(t *testing.T) {
 	testCases := []struct {
 		name   string
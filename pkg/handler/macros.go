@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/plugin"
+)
+
+var (
+	// macroRegexp matches raw query macros with optional arguments, e.g. $__timeFilter(time),
+	// and optional quotes around them, e.g. '$__timeFrom'.
+	macroRegexp = regexp.MustCompile(`('?)\$__(\w+)(?:\(([^)]*)\))?('?)`)
+	// identifierRegexp matches unquoted and quoted CQL identifiers.
+	identifierRegexp = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9_]*|"(?:[^"]|"")+")$`)
+	// durationRegexp matches CQL duration literals, e.g. 1h30m.
+	durationRegexp = regexp.MustCompile(`^(?:\d+(?:y|mo|w|d|h|ms|us|µs|ns|m|s))+$`)
+)
+
+// Formats of the $__timeFrom and $__timeTo macros.
+const (
+	timeFormatDefault = ""
+	timeFormatMillis  = "ms"
+	timeFormatSeconds = "s"
+	timeFormatDate    = "date"
+)
+
+// macroContext contains values the raw query macros are expanded with.
+type macroContext struct {
+	from       time.Time
+	to         time.Time
+	interval   time.Duration
+	bindValues []interface{}
+}
+
+// compileMacros expands macros of the raw query. Time range and template variables
+// are replaced with bind markers and their values are returned in the order of appearance.
+// Interval macros are replaced with literals. A bind marker can't be quoted, so quoted time
+// range macros, e.g. '$__timeFrom', are replaced with literals as well, other quoted macros
+// which are bound are rejected.
+func compileMacros(query string, mc macroContext) (string, []interface{}, error) {
+	var (
+		values []interface{}
+		err    error
+	)
+	compiled := macroRegexp.ReplaceAllStringFunc(query, func(quotedMacro string) string {
+		if err != nil {
+			return quotedMacro
+		}
+
+		match := macroRegexp.FindStringSubmatch(quotedMacro)
+		quoted := match[1] != "" && match[4] != ""
+		macro := quotedMacro[len(match[1]) : len(quotedMacro)-len(match[4])]
+		name, hasArgs := match[2], strings.HasSuffix(macro, ")")
+		var args []string
+		if hasArgs {
+			args = splitMacroArgs(match[3])
+		}
+		bound := len(values)
+
+		var (
+			expanded  string
+			expandErr error
+		)
+		switch name {
+		case "timeFrom", "timeTo":
+			if len(args) > 1 {
+				expandErr = fmt.Errorf("expects at most 1 argument, got %d", len(args))
+				break
+			}
+			format := timeFormatDefault
+			if len(args) == 1 {
+				format = args[0]
+			}
+			t := mc.from
+			if name == "timeTo" {
+				t = mc.to
+			}
+			var val interface{}
+			val, expandErr = formatTime(t, format)
+			values = append(values, val)
+			expanded = "?"
+		case "unixEpochFrom", "unixEpochTo":
+			if hasArgs {
+				expandErr = fmt.Errorf("expects no arguments")
+				break
+			}
+			t := mc.from
+			if name == "unixEpochTo" {
+				t = mc.to
+			}
+			values = append(values, t.Unix())
+			expanded = "?"
+		case "timeFilter":
+			if len(args) < 1 || len(args) > 2 {
+				expandErr = fmt.Errorf("expects column and optional format arguments, got %d arguments", len(args))
+				break
+			}
+			if !identifierRegexp.MatchString(args[0]) {
+				expandErr = fmt.Errorf("invalid column name %q", args[0])
+				break
+			}
+			format := timeFormatDefault
+			if len(args) == 2 {
+				format = args[1]
+			}
+			var from, to interface{}
+			if from, expandErr = formatTime(mc.from, format); expandErr != nil {
+				break
+			}
+			if to, expandErr = formatTime(mc.to, format); expandErr != nil {
+				break
+			}
+			values = append(values, from, to)
+			expanded = fmt.Sprintf("%s >= ? AND %s <= ?", args[0], args[0])
+		case "interval", "interval_ms":
+			if hasArgs {
+				expandErr = fmt.Errorf("expects no arguments")
+				break
+			}
+			if name == "interval" {
+				expanded = formatDuration(mc.interval)
+			} else {
+				expanded = strconv.FormatInt(mc.interval.Milliseconds(), 10)
+			}
+		case "timeGroup":
+			if len(args) != 2 {
+				expandErr = fmt.Errorf("expects column and interval arguments, got %d arguments", len(args))
+				break
+			}
+			if !identifierRegexp.MatchString(args[0]) {
+				expandErr = fmt.Errorf("invalid column name %q", args[0])
+				break
+			}
+			interval := args[1]
+			if interval == "$__interval" || interval == "auto" {
+				interval = formatDuration(mc.interval)
+			}
+			if !durationRegexp.MatchString(interval) {
+				expandErr = fmt.Errorf("invalid interval %q", args[1])
+				break
+			}
+			expanded = fmt.Sprintf("floor(%s, %s)", args[0], interval)
+		case "timeBuckets":
+			if len(args) < 1 || len(args) > 2 {
+				expandErr = fmt.Errorf("expects bucket size and optional format arguments, got %d arguments", len(args))
+				break
+			}
+			format := ""
+			if len(args) == 2 {
+				format = args[1]
+			}
+			var buckets []string
+			buckets, expandErr = plugin.TimeBuckets(mc.from, mc.to, args[0], format)
+			values = append(values, buckets)
+			expanded = "?"
+		case "bind":
+			if len(args) != 1 {
+				expandErr = fmt.Errorf("expects 1 argument, got %d", len(args))
+				break
+			}
+			var val interface{}
+			val, expandErr = bindValue(mc.bindValues, args[0])
+			values = append(values, val)
+			expanded = "?"
+		default:
+			expandErr = fmt.Errorf("unknown macro")
+		}
+		if expandErr == nil && quoted && expanded == "?" {
+			switch name {
+			case "timeFrom", "timeTo", "unixEpochFrom", "unixEpochTo":
+				expanded = literalValue(values[bound])
+				values = values[:bound]
+			default:
+				expandErr = fmt.Errorf("bound value must not be quoted")
+			}
+		}
+		if expandErr != nil {
+			err = fmt.Errorf("macro %s: %w", macro, expandErr)
+			return quotedMacro
+		}
+
+		return match[1] + expanded + match[4]
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return compiled, values, nil
+}
+
+// splitMacroArgs splits comma separated macro arguments.
+func splitMacroArgs(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	args := strings.Split(s, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	return args
+}
+
+// formatTime returns a value of the time macro in the given format.
+func formatTime(t time.Time, format string) (interface{}, error) {
+	switch format {
+	case timeFormatDefault:
+		return cassandra.Timestamp(t), nil
+	case timeFormatMillis:
+		return t.UnixMilli(), nil
+	case timeFormatSeconds:
+		return t.Unix(), nil
+	case timeFormatDate:
+		return t.UTC().Format("2006-01-02"), nil
+	default:
+		return nil, fmt.Errorf("unsupported time format %q", format)
+	}
+}
+
+// literalValue returns the time macro value as it's pasted into the query text,
+// timestamps are milliseconds since epoch.
+func literalValue(val interface{}) string {
+	if ts, ok := val.(cassandra.Timestamp); ok {
+		return strconv.FormatInt(time.Time(ts).UnixMilli(), 10)
+	}
+
+	return fmt.Sprintf("%v", val)
+}
+
+// formatDuration returns a CQL duration literal, intervals
+// shorter than a millisecond are rounded up to it.
+func formatDuration(d time.Duration) string {
+	if d%time.Second == 0 && d > 0 {
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
+
+// bindValue returns a template variable value of the $__bind macro with given index.
+func bindValue(bindValues []interface{}, index string) (interface{}, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(bindValues) {
+		return nil, fmt.Errorf("no value for index %s", index)
+	}
+
+	switch v := bindValues[i].(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			list = append(list, fmt.Sprintf("%v", item))
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compileMacros(t *testing.T) {
+	mc := macroContext{
+		from:       time.Unix(1257894000, 0),
+		to:         time.Unix(1257894010, 0),
+		interval:   time.Minute,
+		bindValues: []interface{}{"O'Brien", []interface{}{"a", "b"}},
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		wantQuery  string
+		wantValues []interface{}
+		wantErr    string
+	}{
+		{
+			name:      "no macros",
+			query:     "SELECT * FROM ks.tbl",
+			wantQuery: "SELECT * FROM ks.tbl",
+		},
+		{
+			name:       "time range",
+			query:      "SELECT * FROM ks.tbl WHERE time > $__timeFrom AND time < $__timeTo",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE time > ? AND time < ?",
+			wantValues: []interface{}{cassandra.Timestamp(mc.from), cassandra.Timestamp(mc.to)},
+		},
+		{
+			name:       "time range formats",
+			query:      "SELECT * FROM ks.tbl WHERE day >= $__timeFrom(date) AND sec < $__timeTo(s) AND ms < $__timeTo(ms)",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE day >= ? AND sec < ? AND ms < ?",
+			wantValues: []interface{}{"2009-11-10", int64(1257894010), int64(1257894010000)},
+		},
+		{
+			name:       "quoted time range",
+			query:      "SELECT * FROM ks.tbl WHERE time > '$__timeFrom' AND day < '$__timeTo(date)' AND sec < '$__unixEpochTo'",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE time > '1257894000000' AND day < '2009-11-10' AND sec < '1257894010'",
+			wantValues: []interface{}{},
+		},
+		{
+			name:    "quoted bind",
+			query:   "SELECT * FROM ks.tbl WHERE name = '$__bind(0)'",
+			wantErr: "macro $__bind(0): bound value must not be quoted",
+		},
+		{
+			name:       "unix epoch",
+			query:      "SELECT * FROM ks.tbl WHERE time > $__unixEpochFrom AND time < $__unixEpochTo",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE time > ? AND time < ?",
+			wantValues: []interface{}{int64(1257894000), int64(1257894010)},
+		},
+		{
+			name:       "time filter",
+			query:      "SELECT * FROM ks.tbl WHERE id = 1 AND $__timeFilter(registered_at)",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE id = 1 AND registered_at >= ? AND registered_at <= ?",
+			wantValues: []interface{}{cassandra.Timestamp(mc.from), cassandra.Timestamp(mc.to)},
+		},
+		{
+			name:       "time filter with format",
+			query:      "SELECT * FROM ks.tbl WHERE id = 1 AND $__timeFilter(\"Time\", s)",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE id = 1 AND \"Time\" >= ? AND \"Time\" <= ?",
+			wantValues: []interface{}{int64(1257894000), int64(1257894010)},
+		},
+		{
+			name:      "interval",
+			query:     "SELECT id, $__interval_ms, '$__interval' FROM ks.tbl",
+			wantQuery: "SELECT id, 60000, '60s' FROM ks.tbl",
+		},
+		{
+			name:       "time group",
+			query:      "SELECT id, max(value), $__timeGroup(time, $__interval) FROM ks.tbl WHERE $__timeFilter(time) GROUP BY id, $__timeGroup(time, 5m)",
+			wantQuery:  "SELECT id, max(value), floor(time, 60s) FROM ks.tbl WHERE time >= ? AND time <= ? GROUP BY id, floor(time, 5m)",
+			wantValues: []interface{}{cassandra.Timestamp(mc.from), cassandra.Timestamp(mc.to)},
+		},
+		{
+			name:       "bind values",
+			query:      "SELECT * FROM ks.tbl WHERE id IN $__bind(1) AND name = $__bind(0) AND time > $__timeFrom",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE id IN ? AND name = ? AND time > ?",
+			wantValues: []interface{}{[]string{"a", "b"}, "O'Brien", cassandra.Timestamp(mc.from)},
+		},
+		{
+			name:       "time buckets",
+			query:      "SELECT * FROM ks.tbl WHERE id = 1 AND day IN $__timeBuckets(day) AND month IN $__timeBuckets(month, YYYYMM)",
+			wantQuery:  "SELECT * FROM ks.tbl WHERE id = 1 AND day IN ? AND month IN ?",
+			wantValues: []interface{}{[]string{"2009-11-10"}, []string{"200911"}},
+		},
+		{
+			name:    "invalid bucket size",
+			query:   "SELECT * FROM ks.tbl WHERE id = 1 AND week IN $__timeBuckets(week)",
+			wantErr: "macro $__timeBuckets(week): unsupported bucket size: week",
+		},
+		{
+			name:    "missing bind value",
+			query:   "SELECT * FROM ks.tbl WHERE id = $__bind(2)",
+			wantErr: "macro $__bind(2): no value for index 2",
+		},
+		{
+			name:    "unclosed time filter",
+			query:   "SELECT * FROM ks.tbl WHERE $__timeFilter(time",
+			wantErr: "macro $__timeFilter: expects column and optional format arguments, got 0 arguments",
+		},
+		{
+			name:    "invalid column",
+			query:   "SELECT * FROM ks.tbl WHERE $__timeFilter(time AND id = 1)",
+			wantErr: `macro $__timeFilter(time AND id = 1): invalid column name "time AND id = 1"`,
+		},
+		{
+			name:    "invalid time format",
+			query:   "SELECT * FROM ks.tbl WHERE time > $__timeFrom(ns)",
+			wantErr: `macro $__timeFrom(ns): unsupported time format "ns"`,
+		},
+		{
+			name:    "invalid interval",
+			query:   "SELECT floor(time), $__timeGroup(time, 1 minute) FROM ks.tbl",
+			wantErr: `macro $__timeGroup(time, 1 minute): invalid interval "1 minute"`,
+		},
+		{
+			name:    "unknown macro",
+			query:   "SELECT * FROM ks.tbl WHERE time > $__timeStart",
+			wantErr: "macro $__timeStart: unknown macro",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, values, err := compileMacros(tc.query, mc)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, query)
+			assert.Equal(t, tc.wantValues, values)
+		})
+	}
+}
+
+func Test_formatDuration(t *testing.T) {
+	assert.Equal(t, "60s", formatDuration(time.Minute))
+	assert.Equal(t, "1500ms", formatDuration(1500*time.Millisecond))
+	assert.Equal(t, "1ms", formatDuration(0))
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/handler"
@@ -59,6 +60,12 @@ func newDataSource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		Timeout:               dss.Timeout,
 		TLSConfig:             tlsConfig,
 		AllowedAuthenticators: allowedAuthenticators,
+		ExactNumbers:          dss.ExactNumbers,
+		Limits: cassandra.SelectOptions{
+			PageSize: dss.PageSize,
+			MaxRows:  dss.MaxRows,
+			MaxBytes: dss.MaxBytes,
+		},
 	}
 
 	session, err := cassandra.New(sessionSettings)
@@ -67,7 +74,12 @@ func newDataSource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		return nil, fmt.Errorf("Failed to create Cassandra connection, check Grafana logs for more details")
 	}
 
-	return plugin.New(session), nil
+	return plugin.New(session, plugin.Options{
+		ConcurrentQueries:   dss.ConcurrentQueries,
+		CacheTTL:            time.Duration(dss.CacheTTL) * time.Second,
+		CacheSize:           dss.CacheSize,
+		CommentFieldConfigs: dss.CommentFieldConfigs,
+	}), nil
 }
 
 func main() {
@@ -0,0 +1,248 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Aggregation functions supported by the strict query downsampling.
+const (
+	aggregationAvg   = "avg"
+	aggregationMin   = "min"
+	aggregationMax   = "max"
+	aggregationSum   = "sum"
+	aggregationCount = "count"
+	aggregationLast  = "last"
+)
+
+// isNativeAggregation reports whether the aggregation function is
+// also available as a cassandra native aggregate.
+func isNativeAggregation(function string) bool {
+	switch function {
+	case aggregationAvg, aggregationMin, aggregationMax, aggregationSum, aggregationCount:
+		return true
+	}
+
+	return false
+}
+
+// minBucketInterval is used when neither interval nor max data points are known.
+const minBucketInterval = time.Second
+
+// bucket accumulates values of a single time bucket.
+type bucket struct {
+	count    int64
+	sum      float64
+	min      float64
+	max      float64
+	last     float64
+	lastTime time.Time
+}
+
+func (b *bucket) add(val float64, ts time.Time) {
+	if b.count == 0 || val < b.min {
+		b.min = val
+	}
+	if b.count == 0 || val > b.max {
+		b.max = val
+	}
+	if b.count == 0 || !ts.Before(b.lastTime) {
+		b.last, b.lastTime = val, ts
+	}
+	b.sum += val
+	b.count++
+}
+
+func (b *bucket) value(function string) float64 {
+	switch function {
+	case aggregationMin:
+		return b.min
+	case aggregationMax:
+		return b.max
+	case aggregationSum:
+		return b.sum
+	case aggregationCount:
+		return float64(b.count)
+	case aggregationLast:
+		return b.last
+	default:
+		return b.sum / float64(b.count)
+	}
+}
+
+// series keeps buckets of a single time series, one bucket per value column.
+type series struct {
+	template cassandra.Row
+	buckets  map[int64][]*bucket
+}
+
+// aggregator downsamples rows of the strict query while they are streamed
+// from repository, so only buckets are kept in memory. Bucket start times
+// are aligned to the interval. Zero interval reduces all rows of a series
+// to a single bucket.
+type aggregator struct {
+	function     string
+	interval     time.Duration
+	valueColumns []string
+	timeColumn   string
+	series       map[string]*series
+	// ids is the order of the series, the requested IDs, as the rows
+	// of a parallel query arrive in a nondeterministic order
+	ids []string
+}
+
+func newAggregator(q *Query) (*aggregator, error) {
+	switch q.Aggregation {
+	case aggregationAvg, aggregationMin, aggregationMax, aggregationSum, aggregationCount, aggregationLast:
+	default:
+		return nil, fmt.Errorf("unsupported aggregation: %s", q.Aggregation)
+	}
+
+	return &aggregator{
+		function:     q.Aggregation,
+		interval:     q.bucketInterval(),
+		valueColumns: q.valueColumns(),
+		timeColumn:   q.ColumnTime,
+		series:       make(map[string]*series),
+		ids:          splitIDs(q.ValueID),
+	}, nil
+}
+
+// add puts the row values to the corresponding buckets. NULL values are skipped.
+func (a *aggregator) add(id string, row cassandra.Row) error {
+	ts, ok := row.Fields[a.timeColumn].(time.Time)
+	if !ok {
+		return fmt.Errorf("time column %s has unsupported type %T", a.timeColumn, row.Fields[a.timeColumn])
+	}
+
+	s, ok := a.series[id]
+	if !ok {
+		s = &series{template: row, buckets: make(map[int64][]*bucket)}
+		a.series[id] = s
+	}
+
+	var start int64
+	if a.interval > 0 {
+		start = bucketStart(ts, a.interval)
+	}
+	for i, column := range a.valueColumns {
+		rawValue := row.Fields[column]
+		if rawValue == nil {
+			continue
+		}
+		val, ok := toFloat64(rawValue)
+		if !ok {
+			return fmt.Errorf("value column %s is not numeric: %T", column, rawValue)
+		}
+
+		buckets, ok := s.buckets[start]
+		if !ok {
+			buckets = make([]*bucket, len(a.valueColumns))
+			s.buckets[start] = buckets
+		}
+		if buckets[i] == nil {
+			buckets[i] = &bucket{}
+		}
+		buckets[i].add(val, ts)
+	}
+
+	return nil
+}
+
+// bucketStart returns the start of the interval bucket of the time in nanoseconds.
+// Buckets are aligned to the Unix epoch the same way as by the floor function of
+// Cassandra, unlike time.Truncate, which aligns them to the zero time.
+func bucketStart(ts time.Time, interval time.Duration) int64 {
+	nanos := ts.UnixNano()
+	start := nanos - nanos%int64(interval)
+	if start > nanos {
+		// the remainder of times before the epoch is negative
+		start -= int64(interval)
+	}
+
+	return start
+}
+
+// result returns one row per bucket ordered by time for every series.
+// Value columns without values in a bucket are NULL. Series follow the
+// order of the requested IDs, the rest of them are sorted by ID.
+func (a *aggregator) result() *cassandra.Result {
+	result := &cassandra.Result{Rows: make(map[string][]cassandra.Row, len(a.series))}
+	for _, id := range a.ids {
+		if s, ok := a.series[id]; ok && len(s.buckets) > 0 {
+			result.IDs = append(result.IDs, id)
+		}
+	}
+	for id, s := range a.series {
+		if len(s.buckets) == 0 {
+			continue
+		}
+
+		starts := make([]int64, 0, len(s.buckets))
+		for start := range s.buckets {
+			starts = append(starts, start)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+		types := make(map[string]data.FieldType, len(s.template.Types))
+		for k, v := range s.template.Types {
+			types[k] = v
+		}
+		for _, column := range a.valueColumns {
+			types[column] = data.FieldTypeFloat64
+		}
+
+		units := s.template.Units
+		if a.function == aggregationCount {
+			units = nil
+		}
+
+		rows := make([]cassandra.Row, 0, len(starts))
+		for _, start := range starts {
+			fields := make(map[string]interface{}, len(s.template.Fields))
+			for k, v := range s.template.Fields {
+				fields[k] = v
+			}
+			for i, column := range a.valueColumns {
+				fields[column] = nil
+				if b := s.buckets[start][i]; b != nil {
+					fields[column] = b.value(a.function)
+				}
+			}
+			fields[a.timeColumn] = time.Unix(0, start).UTC()
+			rows = append(rows, cassandra.Row{
+				Columns: s.template.Columns,
+				Fields:  fields,
+				Types:   types,
+				Units:   units,
+			})
+		}
+		result.Rows[id] = rows
+	}
+
+	return result
+}
+
+// toFloat64 converts numeric values produced by repository to float64.
+func toFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func aggregationTestRows() []cassandra.Row {
+	var rows []cassandra.Row
+	values := []interface{}{1.0, 5.0, nil, 3.0, 2.0, 4.0}
+	for i, val := range values {
+		rows = append(rows, cassandra.Row{
+			Columns: []string{"ID", "Value", "Time"},
+			Fields:  map[string]interface{}{"ID": "1", "Value": val, "Time": time.UnixMilli(1257894000000 + int64(i)*20000).UTC()},
+			Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Value": data.FieldTypeFloat64, "Time": data.FieldTypeTime},
+		})
+	}
+
+	return rows
+}
+
+func Test_aggregator(t *testing.T) {
+	testCases := []struct {
+		function string
+		want     []float64
+	}{
+		{function: aggregationAvg, want: []float64{3, 3}},
+		{function: aggregationMin, want: []float64{1, 2}},
+		{function: aggregationMax, want: []float64{5, 4}},
+		{function: aggregationSum, want: []float64{6, 9}},
+		{function: aggregationCount, want: []float64{2, 3}},
+		{function: aggregationLast, want: []float64{5, 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.function, func(t *testing.T) {
+			agg, err := newAggregator(&Query{
+				ColumnValue: "Value",
+				ColumnTime:  "Time",
+				Aggregation: tc.function,
+				Interval:    time.Minute,
+			})
+			assert.NoError(t, err)
+
+			for _, row := range aggregationTestRows() {
+				assert.NoError(t, agg.add("1", row))
+			}
+
+			rows := agg.result().Rows["1"]
+			assert.Len(t, rows, len(tc.want))
+			for i, want := range tc.want {
+				assert.Equal(t, want, rows[i].Fields["Value"])
+				assert.Equal(t, "1", rows[i].Fields["ID"])
+			}
+			assert.Equal(t, time.UnixMilli(1257894000000).UTC(), rows[0].Fields["Time"])
+			assert.Equal(t, time.UnixMilli(1257894060000).UTC(), rows[1].Fields["Time"])
+		})
+	}
+}
+
+func Test_aggregator_valueColumns(t *testing.T) {
+	agg, err := newAggregator(&Query{
+		ColumnValue:  "Temperature",
+		ValueColumns: []ValueColumn{{Column: "Humidity"}},
+		ColumnTime:   "Time",
+		Aggregation:  aggregationSum,
+		Interval:     time.Minute,
+	})
+	assert.NoError(t, err)
+
+	rows := []map[string]interface{}{
+		{"Temperature": 20.0, "Humidity": int32(40), "Time": time.UnixMilli(1257894000000).UTC()},
+		{"Temperature": 21.0, "Humidity": nil, "Time": time.UnixMilli(1257894030000).UTC()},
+		{"Temperature": 22.0, "Humidity": nil, "Time": time.UnixMilli(1257894060000).UTC()},
+	}
+	for _, fields := range rows {
+		assert.NoError(t, agg.add("1", cassandra.Row{Columns: []string{"Temperature", "Humidity", "Time"}, Fields: fields}))
+	}
+
+	result := agg.result().Rows["1"]
+	assert.Len(t, result, 2)
+	assert.Equal(t, 41.0, result[0].Fields["Temperature"])
+	assert.Equal(t, 40.0, result[0].Fields["Humidity"])
+	assert.Equal(t, 22.0, result[1].Fields["Temperature"])
+	assert.Nil(t, result[1].Fields["Humidity"])
+	assert.Equal(t, data.FieldTypeFloat64, result[1].Types["Humidity"])
+}
+
+func Test_aggregator_order(t *testing.T) {
+	agg, err := newAggregator(&Query{
+		ValueID:     "1,2,3",
+		ColumnValue: "Value",
+		ColumnTime:  "Time",
+		Aggregation: aggregationMax,
+		Interval:    time.Minute,
+	})
+	assert.NoError(t, err)
+
+	// rows of a parallel query arrive in any order
+	for _, id := range []string{"4", "2", "1"} {
+		for _, row := range aggregationTestRows() {
+			assert.NoError(t, agg.add(id, row))
+		}
+	}
+
+	assert.Equal(t, []string{"1", "2", "4"}, agg.result().OrderedIDs())
+}
+
+func Test_bucketStart(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ts       time.Time
+		interval time.Duration
+		want     time.Time
+	}{
+		{
+			name:     "aligned",
+			ts:       time.UnixMilli(1257894000000),
+			interval: time.Minute,
+			want:     time.UnixMilli(1257894000000),
+		},
+		{
+			name:     "epoch aligned",
+			ts:       time.UnixMilli(1257894000000),
+			interval: 7 * time.Minute,
+			want:     time.UnixMilli(1257893700000),
+		},
+		{
+			name:     "before epoch",
+			ts:       time.UnixMilli(-90000),
+			interval: time.Minute,
+			want:     time.UnixMilli(-120000),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want.UnixNano(), bucketStart(tc.ts, tc.interval))
+		})
+	}
+}
+
+func Test_aggregator_errors(t *testing.T) {
+	_, err := newAggregator(&Query{Aggregation: "median"})
+	assert.EqualError(t, err, "unsupported aggregation: median")
+
+	agg, err := newAggregator(&Query{ColumnValue: "Value", ColumnTime: "Time", Aggregation: aggregationAvg})
+	assert.NoError(t, err)
+
+	err = agg.add("1", cassandra.Row{Fields: map[string]interface{}{"Value": 1.0, "Time": "now"}})
+	assert.EqualError(t, err, "time column Time has unsupported type string")
+
+	err = agg.add("1", cassandra.Row{Fields: map[string]interface{}{"Value": "one", "Time": time.Now()}})
+	assert.EqualError(t, err, "value column Value is not numeric: string")
+}
+
+func TestPlugin_ExecQuery_aggregation(t *testing.T) {
+	repo := &repositoryMock{
+		onSelectFunc: func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+			for _, row := range aggregationTestRows() {
+				if err := fn("1", row); err != nil {
+					return nil, fmt.Errorf("fn: %w", err)
+				}
+			}
+			return nil, nil
+		},
+		onGetVersion: func(ctx context.Context) (string, error) {
+			return "3.11.4", nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	dataFrames, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:      "Keyspace",
+		Table:         "Table",
+		ColumnValue:   "Value",
+		ColumnID:      "ID",
+		ValueID:       "1",
+		ColumnTime:    "Time",
+		TimeFrom:      time.UnixMilli(1257894000000).UTC(),
+		TimeTo:        time.UnixMilli(1257894120000).UTC(),
+		Aggregation:   aggregationMax,
+		MaxDataPoints: 2,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, data.Frames{
+		{
+			Name: "1",
+			Fields: []*data.Field{
+				data.NewField("ID", nil, []string{"1", "1"}),
+				data.NewField("Value", nil, []float64{5, 4}),
+				data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894000000).UTC(), time.UnixMilli(1257894060000).UTC()}),
+			},
+		},
+	}, dataFrames)
+}
+
+func TestPlugin_ExecQuery_nativeAggregation(t *testing.T) {
+	var statement string
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			statement = query
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{}}, nil
+		},
+		onGetVersion: func(ctx context.Context) (string, error) {
+			return "4.1.3", nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	_, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:    "keyspace",
+		Table:       "table",
+		ColumnValue: "value",
+		ColumnID:    "id",
+		ValueID:     "1",
+		ColumnTime:  "time",
+		Aggregation: aggregationMax,
+		Interval:    time.Minute,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, max(value) AS value, floor(time, 60s) AS time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id, floor(time, 60s)", statement)
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Columns of the annotation query result, names are matched case-insensitively.
+// Only the time column is required.
+const (
+	annotationTime    = "time"
+	annotationTimeEnd = "timeEnd"
+	annotationTitle   = "title"
+	annotationText    = "text"
+	annotationTags    = "tags"
+)
+
+// execAnnotationQuery executes raw annotation query and transforms rows to
+// a frame of the shape expected by grafana annotations.
+func (p *Plugin) execAnnotationQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	var rows []cassandra.Row
+	notices, err := p.repo.SelectFunc(ctx, q.selectOptions(), func(_ string, row cassandra.Row) error {
+		rows = append(rows, row)
+		return nil
+	}, q.Target, q.Values...)
+	if err != nil {
+		return nil, fmt.Errorf("repo.SelectFunc: %w", err)
+	}
+	if len(rows) == 0 {
+		return appendNotices(nil, notices), nil
+	}
+
+	frame, err := makeAnnotationFrame(rows)
+	if err != nil {
+		return nil, fmt.Errorf("makeAnnotationFrame: %w", err)
+	}
+
+	return appendNotices(data.Frames{frame}, notices), nil
+}
+
+// makeAnnotationFrame creates annotations frame with time, timeEnd, title, text
+// and tags fields from the matching columns of the rows. Time values can be
+// timestamps or milliseconds since epoch, tags can be a comma separated text
+// or a list or set of text values.
+func makeAnnotationFrame(rows []cassandra.Row) (*data.Frame, error) {
+	columns := make(map[string]string)
+	for _, colName := range rows[0].Columns {
+		for _, name := range []string{annotationTime, annotationTimeEnd, annotationTitle, annotationText, annotationTags} {
+			if strings.EqualFold(colName, name) {
+				columns[name] = colName
+			}
+		}
+	}
+	if _, ok := columns[annotationTime]; !ok {
+		return nil, fmt.Errorf("%s column is required", annotationTime)
+	}
+
+	var (
+		times    = make([]time.Time, 0, len(rows))
+		timeEnds = make([]*time.Time, 0, len(rows))
+		titles   = make([]string, 0, len(rows))
+		texts    = make([]string, 0, len(rows))
+		tags     = make([]string, 0, len(rows))
+	)
+	for _, row := range rows {
+		t, ok := annotationTimeValue(row.Fields[columns[annotationTime]])
+		if !ok {
+			return nil, fmt.Errorf("%s value %v is not a timestamp", annotationTime, row.Fields[columns[annotationTime]])
+		}
+		times = append(times, t)
+
+		if colName, ok := columns[annotationTimeEnd]; ok {
+			var timeEnd *time.Time
+			if val := row.Fields[colName]; val != nil {
+				t, ok := annotationTimeValue(val)
+				if !ok {
+					return nil, fmt.Errorf("%s value %v is not a timestamp", annotationTimeEnd, val)
+				}
+				timeEnd = &t
+			}
+			timeEnds = append(timeEnds, timeEnd)
+		}
+
+		titles = append(titles, textValue(row.Fields[columns[annotationTitle]]))
+		texts = append(texts, textValue(row.Fields[columns[annotationText]]))
+		tags = append(tags, annotationTagsValue(row, columns[annotationTags]))
+	}
+
+	frame := data.NewFrame("annotations", data.NewField(annotationTime, nil, times))
+	if _, ok := columns[annotationTimeEnd]; ok {
+		frame.Fields = append(frame.Fields, data.NewField(annotationTimeEnd, nil, timeEnds))
+	}
+	frame.Fields = append(frame.Fields,
+		data.NewField(annotationTitle, nil, titles),
+		data.NewField(annotationText, nil, texts),
+		data.NewField(annotationTags, nil, tags),
+	)
+
+	return frame, nil
+}
+
+func annotationTimeValue(val interface{}) (time.Time, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+	case int64:
+		return time.UnixMilli(v).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// textValue returns a string presentation of the value, NULL is an empty string.
+func textValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// annotationTagsValue returns comma separated tags of the row, which grafana splits into a list.
+func annotationTagsValue(row cassandra.Row, colName string) string {
+	list, ok := row.Collections[colName].([]interface{})
+	if !ok {
+		return textValue(row.Fields[colName])
+	}
+
+	tags := make([]string, 0, len(list))
+	for _, tag := range list {
+		tags = append(tags, textValue(tag))
+	}
+
+	return strings.Join(tags, ",")
+}
@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_annotation(t *testing.T) {
+	start := time.UnixMilli(1257894000000).UTC()
+	end := time.UnixMilli(1257894060000).UTC()
+	columns := []string{"time", "timeend", "title", "text", "tags"}
+
+	testCases := []struct {
+		name    string
+		rows    []cassandra.Row
+		want    data.Frames
+		wantErr string
+	}{
+		{
+			name: "all columns",
+			rows: []cassandra.Row{
+				{
+					Columns:     columns,
+					Fields:      map[string]interface{}{"time": start, "timeend": end, "title": "deploy", "text": "v1.2.0", "tags": `["prod","api"]`},
+					Collections: map[string]interface{}{"tags": []interface{}{"prod", "api"}},
+				},
+				{
+					Columns: columns,
+					Fields:  map[string]interface{}{"time": end, "timeend": nil, "title": nil, "text": "restart", "tags": nil},
+				},
+			},
+			want: data.Frames{data.NewFrame("annotations",
+				data.NewField("time", nil, []time.Time{start, end}),
+				data.NewField("timeEnd", nil, []*time.Time{&end, nil}),
+				data.NewField("title", nil, []string{"deploy", ""}),
+				data.NewField("text", nil, []string{"v1.2.0", "restart"}),
+				data.NewField("tags", nil, []string{"prod,api", ""}),
+			)},
+		},
+		{
+			name: "epoch time and text tags",
+			rows: []cassandra.Row{{
+				Columns: []string{"Time", "Text", "Tags"},
+				Fields:  map[string]interface{}{"Time": int64(1257894000000), "Text": "deploy", "Tags": "prod,api"},
+			}},
+			want: data.Frames{data.NewFrame("annotations",
+				data.NewField("time", nil, []time.Time{start}),
+				data.NewField("title", nil, []string{""}),
+				data.NewField("text", nil, []string{"deploy"}),
+				data.NewField("tags", nil, []string{"prod,api"}),
+			)},
+		},
+		{
+			name: "no time column",
+			rows: []cassandra.Row{{
+				Columns: []string{"text"},
+				Fields:  map[string]interface{}{"text": "deploy"},
+			}},
+			wantErr: "query processing: makeAnnotationFrame: time column is required",
+		},
+		{
+			name: "invalid time",
+			rows: []cassandra.Row{{
+				Columns: []string{"time"},
+				Fields:  map[string]interface{}{"time": "yesterday"},
+			}},
+			wantErr: "query processing: makeAnnotationFrame: time value yesterday is not a timestamp",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &repositoryMock{
+				onSelectFunc: func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+					assert.Equal(t, "SELECT time, timeend, title, text, tags FROM ks.events", query)
+					for _, row := range tc.rows {
+						if err := fn("", row); err != nil {
+							return nil, err
+						}
+					}
+					return nil, nil
+				},
+			}
+
+			p := &Plugin{repo: repo}
+			frames, err := p.ExecQuery(context.TODO(), &Query{
+				Target:            "SELECT time, timeend, title, text, tags FROM ks.events",
+				IsAnnotationQuery: true,
+			})
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, frames)
+		})
+	}
+}
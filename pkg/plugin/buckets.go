@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+)
+
+// Sizes of the time buckets used in bucketed partition keys.
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketMonth = "month"
+)
+
+// maxTimeBuckets limits the number of buckets, so a large time range
+// doesn't produce a huge IN list.
+const maxTimeBuckets = 1000
+
+// bucketFormats are default bucket formats, e.g. 2009-11-10 for the
+// daily buckets, which could be bound to a date or text column.
+var bucketFormats = map[string]string{
+	BucketHour:  "YYYY-MM-DDTHH",
+	BucketDay:   "YYYY-MM-DD",
+	BucketMonth: "YYYY-MM",
+}
+
+// TimeBuckets returns formatted values of all the time buckets which intersect
+// the time range. Format uses YYYY, MM, DD and HH placeholders, e.g. YYYY-MM-DD,
+// default format of the bucket size is used when it's empty.
+func TimeBuckets(from, to time.Time, size, format string) ([]string, error) {
+	defaultFormat, ok := bucketFormats[size]
+	if !ok {
+		return nil, fmt.Errorf("unsupported bucket size: %s", size)
+	}
+	if format == "" {
+		format = defaultFormat
+	}
+
+	from, to = from.UTC(), to.UTC()
+	var (
+		start time.Time
+		next  func(t time.Time) time.Time
+	)
+	switch size {
+	case BucketHour:
+		start = from.Truncate(time.Hour)
+		next = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case BucketDay:
+		start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case BucketMonth:
+		start = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	}
+
+	var buckets []string
+	for t := start; !t.After(to); t = next(t) {
+		if len(buckets) == maxTimeBuckets {
+			return nil, fmt.Errorf("time range covers more than %d %s buckets", maxTimeBuckets, size)
+		}
+		buckets = append(buckets, formatBucket(t, format))
+	}
+
+	return buckets, nil
+}
+
+// formatBucket replaces YYYY, MM, DD and HH placeholders of the format with the time
+// values, the rest of the format is copied as is, e.g. s1_YYYYMMDD gives s1_20091110.
+func formatBucket(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		switch {
+		case strings.HasPrefix(format[i:], "YYYY"):
+			fmt.Fprintf(&b, "%04d", t.Year())
+			i += 4
+		case strings.HasPrefix(format[i:], "MM"):
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+			i += 2
+		case strings.HasPrefix(format[i:], "DD"):
+			fmt.Fprintf(&b, "%02d", t.Day())
+			i += 2
+		case strings.HasPrefix(format[i:], "HH"):
+			fmt.Fprintf(&b, "%02d", t.Hour())
+			i += 2
+		default:
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// sortRowsByTime sorts rows of every series by the time column. Rows of the different
+// partitions are returned in the token order, so bucketed series are not ordered by time.
+func sortRowsByTime(result *cassandra.Result, timeColumn string) {
+	for _, rows := range result.Rows {
+		sort.SliceStable(rows, func(i, j int) bool {
+			ti, _ := rows[i].Fields[timeColumn].(time.Time)
+			tj, _ := rows[j].Fields[timeColumn].(time.Time)
+			return ti.Before(tj)
+		})
+	}
+}
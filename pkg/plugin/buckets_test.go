@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	from := time.Date(2009, time.November, 10, 22, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		to      time.Time
+		size    string
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "hours",
+			to:   from.Add(2 * time.Hour),
+			size: BucketHour,
+			want: []string{"2009-11-10T22", "2009-11-10T23", "2009-11-11T00"},
+		},
+		{
+			name: "days",
+			to:   from.Add(26 * time.Hour),
+			size: BucketDay,
+			want: []string{"2009-11-10", "2009-11-11", "2009-11-12"},
+		},
+		{
+			name:   "months with format",
+			to:     from.AddDate(0, 2, 0),
+			size:   BucketMonth,
+			format: "YYYYMM",
+			want:   []string{"200911", "200912", "201001"},
+		},
+		{
+			name:   "prefixed format",
+			to:     from.Add(26 * time.Hour),
+			size:   BucketDay,
+			format: "s1_YYYYMMDD",
+			want:   []string{"s1_20091110", "s1_20091111", "s1_20091112"},
+		},
+		{
+			name:   "go layout tokens",
+			to:     from.Add(time.Hour),
+			size:   BucketHour,
+			format: "Mon-Jan-2006-YYYY.MM.DD.HH",
+			want:   []string{"Mon-Jan-2006-2009.11.10.22", "Mon-Jan-2006-2009.11.10.23"},
+		},
+		{
+			name: "same bucket",
+			to:   from.Add(time.Minute),
+			size: BucketDay,
+			want: []string{"2009-11-10"},
+		},
+		{
+			name:    "unsupported size",
+			to:      from,
+			size:    "week",
+			wantErr: "unsupported bucket size: week",
+		},
+		{
+			name:    "too many buckets",
+			to:      from.AddDate(1, 0, 0),
+			size:    BucketHour,
+			wantErr: "time range covers more than 1000 hour buckets",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buckets, err := TimeBuckets(from, tc.to, tc.size, tc.format)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, buckets)
+		})
+	}
+}
+
+func TestPlugin_ExecQuery_buckets(t *testing.T) {
+	var (
+		statement string
+		bound     []interface{}
+	)
+	row := func(ts int64) cassandra.Row {
+		return cassandra.Row{
+			Columns: []string{"id", "value", "time"},
+			Fields:  map[string]interface{}{"id": "1", "value": 1.0, "time": time.UnixMilli(ts).UTC()},
+		}
+	}
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			statement, bound = query, values
+			// partitions are returned in the token order
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{"1": {row(1257984000000), row(1257894000000)}}}, nil
+		},
+	}
+
+	from, to := time.UnixMilli(1257894000000).UTC(), time.UnixMilli(1257984000000).UTC()
+	p := &Plugin{repo: repo}
+	frames, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:     "keyspace",
+		Table:        "table",
+		ColumnValue:  "value",
+		ColumnID:     "id",
+		ValueID:      "1",
+		ColumnTime:   "time",
+		TimeFrom:     from,
+		TimeTo:       to,
+		BucketColumn: "day",
+		BucketSize:   BucketDay,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, value, time FROM keyspace.table WHERE id IN ? AND day IN ? AND time >= ? AND time <= ?", statement)
+	assert.Equal(t, []interface{}{[]string{"1"}, []string{"2009-11-10", "2009-11-11", "2009-11-12"}, cassandra.Timestamp(from), cassandra.Timestamp(to)}, bound)
+	assert.Equal(t, time.UnixMilli(1257894000000).UTC(), frames[0].Fields[2].At(0))
+	assert.Equal(t, time.UnixMilli(1257984000000).UTC(), frames[0].Fields[2].At(1))
+}
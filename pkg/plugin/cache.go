@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultCacheSize is used when the cache size is not set.
+const defaultCacheSize = 1000
+
+// cacheQueryTimeout limits a query shared by the callers, see sharedCall.
+const cacheQueryTimeout = 5 * time.Minute
+
+// cacheEntry is a cached select result.
+type cacheEntry struct {
+	key     string
+	result  *cassandra.Result
+	expires time.Time
+}
+
+// cachedRepository caches results of the select queries in memory. Time range bounds
+// of the query, which are bound as cassandra.Timestamp, are aligned to the cache TTL in
+// the cache key, so queries of a slightly different time range, e.g. made by several
+// dashboards refreshed at different moments, share the same entry. The query itself is
+// executed with the exact values. Least recently used entries are evicted when the cache is full.
+type cachedRepository struct {
+	repository
+
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	group   singleflight.Group
+}
+
+func newCachedRepository(repo repository, ttl time.Duration, size int) *cachedRepository {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+
+	return &cachedRepository{
+		repository: repo,
+		ttl:        ttl,
+		size:       size,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Select returns cached result of the query or queries repository and caches the result.
+// Concurrent identical queries are executed only once. SelectFunc results are not cached.
+func (c *cachedRepository) Select(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+	if opts.NoCache {
+		return c.repository.Select(ctx, opts, query, values...)
+	}
+
+	key := cacheKey(opts, query, c.alignValues(values))
+	if result, ok := c.get(key); ok {
+		return result, nil
+	}
+
+	result, err := sharedCall(ctx, &c.group, key, cacheQueryTimeout, func(ctx context.Context) (interface{}, error) {
+		result, err := c.repository.Select(ctx, opts, query, values...)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, result)
+
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return copyResult(result.(*cassandra.Result)), nil
+}
+
+func (c *cachedRepository) get(key string) (*cassandra.Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+
+	return copyResult(entry.result), true
+}
+
+func (c *cachedRepository) put(key string, result *cassandra.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: result, expires: c.now().Add(c.ttl)})
+
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// alignValues truncates time range bounds to the cache TTL. Other values,
+// e.g. time values of filters, are kept as is.
+func (c *cachedRepository) alignValues(values []interface{}) []interface{} {
+	aligned := make([]interface{}, len(values))
+	for i, val := range values {
+		if ts, ok := val.(cassandra.Timestamp); ok {
+			val = cassandra.Timestamp(time.Time(ts).Truncate(c.ttl))
+		}
+		aligned[i] = val
+	}
+
+	return aligned
+}
+
+// cacheKey returns a hash of the query, its options and bound values.
+func cacheKey(opts cassandra.SelectOptions, query string, values []interface{}) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%#v\x00%s", opts, query)
+	for _, val := range values {
+		typ := fmt.Sprintf("%T", val)
+		if ts, ok := val.(cassandra.Timestamp); ok {
+			// unlike Timestamp, time.Time is formatted without the internal fields
+			val = time.Time(ts)
+		}
+		fmt.Fprintf(hash, "\x00%s:%#v", typ, val)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// copyResult returns a copy of the result, which rows could be added to, removed
+// from or reordered without affecting the cached one. Rows themselves are not
+// copied: their Fields, Types and Units maps are shared with the cache and other
+// callers, so they are read-only, see repository.Select.
+func copyResult(result *cassandra.Result) *cassandra.Result {
+	rows := make(map[string][]cassandra.Row, len(result.Rows))
+	for id, idRows := range result.Rows {
+		rows[id] = append([]cassandra.Row(nil), idRows...)
+	}
+
+	return &cassandra.Result{
+		Rows:    rows,
+		IDs:     append([]string(nil), result.IDs...),
+		Notices: append([]string(nil), result.Notices...),
+	}
+}
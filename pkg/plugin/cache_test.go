@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedRepository_Select(t *testing.T) {
+	var calls int
+	var bound []interface{}
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			calls++
+			bound = values
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{
+				"1": {{Columns: []string{"id", "value"}, Fields: map[string]interface{}{"id": "1", "value": 1.0}}},
+			}}, nil
+		},
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newCachedRepository(repo, time.Minute, 0)
+	cache.now = func() time.Time { return now }
+
+	from := time.Date(2024, 1, 1, 11, 0, 10, 0, time.UTC)
+	to := time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)
+	result, err := cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "1", cassandra.Timestamp(from), cassandra.Timestamp(to))
+	assert.NoError(t, err)
+	assert.Len(t, result.Rows["1"], 1)
+	assert.Equal(t, 1, calls)
+	// the query is executed with the exact time range
+	assert.Equal(t, []interface{}{"1", cassandra.Timestamp(from), cassandra.Timestamp(to)}, bound)
+
+	// the result is a copy
+	result.Rows["1"] = nil
+
+	// time range within the same minute hits the cache
+	result, err = cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "1",
+		cassandra.Timestamp(from.Add(20*time.Second)), cassandra.Timestamp(to.Add(20*time.Second)))
+	assert.NoError(t, err)
+	assert.Len(t, result.Rows["1"], 1)
+	assert.Equal(t, 1, calls)
+
+	// other values miss the cache
+	_, err = cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "2", cassandra.Timestamp(from), cassandra.Timestamp(to))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// time values other than range bounds, e.g. filters, are not aligned
+	_, err = cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "1", cassandra.Timestamp(from), cassandra.Timestamp(to), from)
+	assert.NoError(t, err)
+	_, err = cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "1", cassandra.Timestamp(from), cassandra.Timestamp(to), from.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, []interface{}{"1", cassandra.Timestamp(from), cassandra.Timestamp(to), from.Add(time.Second)}, bound)
+
+	// no cache option bypasses the cache
+	_, err = cache.Select(context.TODO(), cassandra.SelectOptions{NoCache: true}, "query", "1", cassandra.Timestamp(from), cassandra.Timestamp(to))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	// expired entry is queried again
+	now = now.Add(2 * time.Minute)
+	_, err = cache.Select(context.TODO(), cassandra.SelectOptions{}, "query", "1", cassandra.Timestamp(from), cassandra.Timestamp(to))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, calls)
+}
+
+func TestCachedRepository_Select_cancelled(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{}}, nil
+		},
+	}
+
+	cache := newCachedRepository(repo, time.Minute, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := cache.Select(ctx, cassandra.SelectOptions{}, "query")
+		leaderErr <- err
+	}()
+	<-started
+
+	// the caller which started the query is cancelled, but the query is shared with others
+	followerErr := make(chan error)
+	go func() {
+		_, err := cache.Select(context.TODO(), cassandra.SelectOptions{}, "query")
+		followerErr <- err
+	}()
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+
+	close(release)
+	assert.NoError(t, <-followerErr)
+}
+
+func TestCachedRepository_Select_eviction(t *testing.T) {
+	var calls int
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			calls++
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{}}, nil
+		},
+	}
+
+	cache := newCachedRepository(repo, time.Minute, 2)
+	for _, query := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := cache.Select(context.TODO(), cassandra.SelectOptions{}, query)
+		assert.NoError(t, err)
+	}
+
+	// "b" is evicted by "c" as least recently used and queried again
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, 2, cache.lru.Len())
+}
+
+func TestCachedRepository_Select_error(t *testing.T) {
+	var calls int
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			calls++
+			return nil, errors.New("timeout")
+		},
+	}
+
+	cache := newCachedRepository(repo, time.Minute, 0)
+	for i := 0; i < 2; i++ {
+		_, err := cache.Select(context.TODO(), cassandra.SelectOptions{}, "query")
+		assert.EqualError(t, err, "timeout")
+	}
+
+	// errors are not cached
+	assert.Equal(t, 2, calls)
+}
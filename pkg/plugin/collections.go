@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// labeledRows is a group of rows sharing the same set of labels.
+type labeledRows struct {
+	labels data.Labels
+	rows   []cassandra.Row
+}
+
+// expandCollections converts map columns to labels and explodes list and set
+// columns, so every element produces a separate row. Multiple list columns
+// are exploded one after another. Rows are grouped by their labels, groups
+// are ordered by the first appearance of the labels set.
+func expandCollections(rows []cassandra.Row) []labeledRows {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	mapColumns, listColumns := collectionColumns(rows)
+
+	// exploded columns change their types, so they are guessed from values
+	types := make(map[string]data.FieldType, len(rows[0].Types))
+	for colName, fieldType := range rows[0].Types {
+		if !listColumns[colName] {
+			types[colName] = fieldType
+		}
+	}
+
+	columns := make([]string, 0, len(rows[0].Columns))
+	for _, colName := range rows[0].Columns {
+		if !mapColumns[colName] {
+			columns = append(columns, colName)
+		}
+	}
+
+	var groups []labeledRows
+	index := make(map[string]int)
+	for _, row := range rows {
+		labels := data.Labels{}
+		fields := make(map[string]interface{}, len(columns))
+		for _, colName := range row.Columns {
+			if !mapColumns[colName] {
+				fields[colName] = row.Fields[colName]
+				continue
+			}
+			m, _ := row.Collections[colName].(map[string]interface{})
+			for k, v := range m {
+				labels[k] = fmt.Sprintf("%v", v)
+			}
+		}
+
+		key := labels.String()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, labeledRows{labels: labels})
+		}
+
+		expanded := cassandra.Row{Columns: columns, Fields: fields, Types: types}
+		groups[i].rows = append(groups[i].rows, explodeLists(expanded, row.Collections)...)
+	}
+
+	return groups
+}
+
+// collectionColumns returns sets of map and list (or set) columns found in rows.
+func collectionColumns(rows []cassandra.Row) (mapColumns, listColumns map[string]bool) {
+	mapColumns = make(map[string]bool)
+	listColumns = make(map[string]bool)
+	for _, row := range rows {
+		for colName, val := range row.Collections {
+			switch val.(type) {
+			case map[string]interface{}:
+				mapColumns[colName] = true
+			case []interface{}:
+				listColumns[colName] = true
+			}
+		}
+	}
+
+	return mapColumns, listColumns
+}
+
+// explodeLists replaces every list column value with its elements producing
+// one row per element. Row with an empty list is kept with nil value.
+func explodeLists(row cassandra.Row, collections map[string]interface{}) []cassandra.Row {
+	rows := []cassandra.Row{row}
+	for _, colName := range row.Columns {
+		list, ok := collections[colName].([]interface{})
+		if !ok {
+			continue
+		}
+
+		exploded := make([]cassandra.Row, 0, len(rows)*len(list))
+		for _, r := range rows {
+			if len(list) == 0 {
+				exploded = append(exploded, withField(r, colName, nil))
+				continue
+			}
+			for _, elem := range list {
+				exploded = append(exploded, withField(r, colName, elem))
+			}
+		}
+		rows = exploded
+	}
+
+	return rows
+}
+
+// withField returns a copy of the row with the field value replaced.
+func withField(row cassandra.Row, colName string, val interface{}) cassandra.Row {
+	fields := make(map[string]interface{}, len(row.Fields))
+	for k, v := range row.Fields {
+		fields[k] = v
+	}
+	fields[colName] = val
+	row.Fields = fields
+
+	return row
+}
+
+// setLabels adds labels to all numeric fields of the frame.
+func setLabels(frame *data.Frame, labels data.Labels) {
+	if frame == nil || len(labels) == 0 {
+		return
+	}
+
+	for _, field := range frame.Fields {
+		if !field.Type().Numeric() {
+			continue
+		}
+		if field.Labels == nil {
+			field.Labels = make(data.Labels, len(labels))
+		}
+		for k, v := range labels {
+			field.Labels[k] = v
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_expandCollections(t *testing.T) {
+	types := map[string]data.FieldType{
+		"ID":      data.FieldTypeString,
+		"Samples": data.FieldTypeString,
+		"Tags":    data.FieldTypeString,
+		"Time":    data.FieldTypeTime,
+	}
+
+	testCases := []struct {
+		name  string
+		input []cassandra.Row
+		want  []labeledRows
+	}{
+		{
+			name:  "empty",
+			input: nil,
+			want:  nil,
+		},
+		{
+			name: "no collections",
+			input: []cassandra.Row{
+				{
+					Columns: []string{"ID", "Value"},
+					Fields:  map[string]interface{}{"ID": "1", "Value": 3.141},
+				},
+			},
+			want: []labeledRows{
+				{
+					labels: data.Labels{},
+					rows: []cassandra.Row{
+						{
+							Columns: []string{"ID", "Value"},
+							Fields:  map[string]interface{}{"ID": "1", "Value": 3.141},
+							Types:   map[string]data.FieldType{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "maps and lists",
+			input: []cassandra.Row{
+				{
+					Columns:     []string{"ID", "Samples", "Tags", "Time"},
+					Fields:      map[string]interface{}{"ID": "1", "Samples": "[0.1,0.2]", "Tags": `{"region":"eu"}`, "Time": time.UnixMilli(1257894000000).UTC()},
+					Types:       types,
+					Collections: map[string]interface{}{"Samples": []interface{}{0.1, 0.2}, "Tags": map[string]interface{}{"region": "eu"}},
+				},
+				{
+					Columns:     []string{"ID", "Samples", "Tags", "Time"},
+					Fields:      map[string]interface{}{"ID": "1", "Samples": "[0.3]", "Tags": `{"region":"us"}`, "Time": time.UnixMilli(1257894001000).UTC()},
+					Types:       types,
+					Collections: map[string]interface{}{"Samples": []interface{}{0.3}, "Tags": map[string]interface{}{"region": "us"}},
+				},
+				{
+					Columns:     []string{"ID", "Samples", "Tags", "Time"},
+					Fields:      map[string]interface{}{"ID": "1", "Samples": nil, "Tags": `{"region":"eu"}`, "Time": time.UnixMilli(1257894002000).UTC()},
+					Types:       types,
+					Collections: map[string]interface{}{"Tags": map[string]interface{}{"region": "eu"}},
+				},
+			},
+			want: []labeledRows{
+				{
+					labels: data.Labels{"region": "eu"},
+					rows: []cassandra.Row{
+						{
+							Columns: []string{"ID", "Samples", "Time"},
+							Fields:  map[string]interface{}{"ID": "1", "Samples": 0.1, "Time": time.UnixMilli(1257894000000).UTC()},
+							Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Tags": data.FieldTypeString, "Time": data.FieldTypeTime},
+						},
+						{
+							Columns: []string{"ID", "Samples", "Time"},
+							Fields:  map[string]interface{}{"ID": "1", "Samples": 0.2, "Time": time.UnixMilli(1257894000000).UTC()},
+							Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Tags": data.FieldTypeString, "Time": data.FieldTypeTime},
+						},
+						{
+							Columns: []string{"ID", "Samples", "Time"},
+							Fields:  map[string]interface{}{"ID": "1", "Samples": nil, "Time": time.UnixMilli(1257894002000).UTC()},
+							Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Tags": data.FieldTypeString, "Time": data.FieldTypeTime},
+						},
+					},
+				},
+				{
+					labels: data.Labels{"region": "us"},
+					rows: []cassandra.Row{
+						{
+							Columns: []string{"ID", "Samples", "Time"},
+							Fields:  map[string]interface{}{"ID": "1", "Samples": 0.3, "Time": time.UnixMilli(1257894001000).UTC()},
+							Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Tags": data.FieldTypeString, "Time": data.FieldTypeTime},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, expandCollections(tc.input))
+		})
+	}
+}
+
+func Test_makeDataFrames_expandCollections(t *testing.T) {
+	rows := map[string][]cassandra.Row{
+		"1": {
+			{
+				Columns:     []string{"ID", "Samples", "Tags", "Time"},
+				Fields:      map[string]interface{}{"ID": "1", "Samples": "[0.1,0.2]", "Tags": `{"region":"eu"}`, "Time": time.UnixMilli(1257894000000).UTC()},
+				Collections: map[string]interface{}{"Samples": []interface{}{0.1, 0.2}, "Tags": map[string]interface{}{"region": "eu"}},
+			},
+		},
+	}
+
+	want := data.Frames{
+		{
+			Name: "1",
+			Fields: []*data.Field{
+				data.NewField("ID", nil, []string{"1", "1"}),
+				data.NewField("Samples", data.Labels{"region": "eu"}, []float64{0.1, 0.2}),
+				data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894000000).UTC(), time.UnixMilli(1257894000000).UTC()}),
+			},
+		},
+	}
+
+	frames, err := makeDataFrames(&Query{ExpandCollections: true}, &cassandra.Result{Rows: rows})
+	assert.NoError(t, err)
+	assert.Equal(t, want, frames)
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"golang.org/x/sync/singleflight"
+)
+
+// fieldConfigPrefix starts the field configs in the table comment.
+const fieldConfigPrefix = "grafana:"
+
+// fieldConfigTTL is the time field configs of a table are kept before the comment is read again.
+const fieldConfigTTL = time.Minute
+
+// fieldConfigTimeout limits the table comment lookup.
+const fieldConfigTimeout = 10 * time.Second
+
+// fieldConfigEntry contains field configs of a single table.
+type fieldConfigEntry struct {
+	configs map[string]*data.FieldConfig
+	expires time.Time
+}
+
+// fieldConfigStore keeps field configs of the tables, so the table comment
+// is not read on every query.
+type fieldConfigStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]fieldConfigEntry
+	group   singleflight.Group
+}
+
+func newFieldConfigStore() *fieldConfigStore {
+	return &fieldConfigStore{now: time.Now, entries: make(map[string]fieldConfigEntry)}
+}
+
+// tableFieldConfigs returns field configs of the table by column names. Errors are logged only,
+// as the field configs are not essential for the query, and the table has no configs until
+// the comment is read again. Concurrent queries of the table share a single lookup, see
+// sharedCall, and a lookup which timed out isn't cached.
+func (p *Plugin) tableFieldConfigs(ctx context.Context, keyspace, table string) map[string]*data.FieldConfig {
+	store := p.fieldConfigs
+	key := keyspace + "." + table
+
+	store.mu.Lock()
+	entry, ok := store.entries[key]
+	store.mu.Unlock()
+	if ok && store.now().Before(entry.expires) {
+		return entry.configs
+	}
+
+	configs, err := sharedCall(ctx, &store.group, key, fieldConfigTimeout, func(ctx context.Context) (interface{}, error) {
+		var configs map[string]*data.FieldConfig
+		comment, err := p.repo.GetTableComment(ctx, keyspace, table)
+		if err != nil {
+			backend.Logger.Warn("Failed to get table comment", "table", key, "error", err)
+			// the comment is read again by the next query
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return configs, nil
+			}
+		} else if configs, err = parseFieldConfigs(comment); err != nil {
+			backend.Logger.Warn("Failed to parse field configs of table comment", "table", key, "error", err)
+		}
+
+		store.mu.Lock()
+		store.entries[key] = fieldConfigEntry{configs: configs, expires: store.now().Add(fieldConfigTTL)}
+		store.mu.Unlock()
+
+		return configs, nil
+	})
+	if err != nil {
+		return nil
+	}
+
+	return configs.(map[string]*data.FieldConfig)
+}
+
+// parseFieldConfigs parses field configs of the columns from the table comment, CQL doesn't
+// support column comments. Configs are a JSON object of the grafana field configs by column
+// names following the grafana: prefix, the text before and after it is ignored, e.g.
+//
+//	Sensor readings. grafana:{"temperature": {"unit": "celsius", "decimals": 1, "min": -40, "max": 85}}
+//
+// Display name of the config is set as DisplayNameFromDS.
+func parseFieldConfigs(comment string) (map[string]*data.FieldConfig, error) {
+	i := strings.Index(comment, fieldConfigPrefix)
+	if i < 0 {
+		return nil, nil
+	}
+
+	var configs map[string]*data.FieldConfig
+	decoder := json.NewDecoder(strings.NewReader(comment[i+len(fieldConfigPrefix):]))
+	if err := decoder.Decode(&configs); err != nil {
+		return nil, fmt.Errorf("decoder.Decode: %w", err)
+	}
+	for _, config := range configs {
+		if config != nil && config.DisplayName != "" {
+			config.DisplayNameFromDS, config.DisplayName = config.DisplayName, ""
+		}
+	}
+
+	return configs, nil
+}
+
+// setFieldConfigs sets configs of the frame fields by their names. Config replaces the field
+// config, but the unit and display name of the field, e.g. set by alias, are kept unless
+// the config has its own unit or the field has no display name.
+func setFieldConfigs(frames data.Frames, configs map[string]*data.FieldConfig) {
+	if len(configs) == 0 {
+		return
+	}
+
+	for _, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		for _, field := range frame.Fields {
+			config, ok := configs[field.Name]
+			if !ok || config == nil {
+				continue
+			}
+
+			fieldConfig := *config
+			if field.Config != nil {
+				if field.Config.DisplayNameFromDS != "" {
+					fieldConfig.DisplayNameFromDS = field.Config.DisplayNameFromDS
+				}
+				if fieldConfig.Unit == "" {
+					fieldConfig.Unit = field.Config.Unit
+				}
+			}
+			field.SetConfig(&fieldConfig)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseFieldConfigs(t *testing.T) {
+	decimals := uint16(1)
+	min, max := data.ConfFloat64(-40), data.ConfFloat64(85)
+
+	testCases := []struct {
+		name    string
+		comment string
+		want    map[string]*data.FieldConfig
+		wantErr string
+	}{
+		{
+			name:    "no configs",
+			comment: "Sensor readings",
+		},
+		{
+			name:    "configs",
+			comment: `Sensor readings. grafana:{"temperature": {"unit": "celsius", "decimals": 1, "min": -40, "max": 85, "displayName": "Temperature"}} Written by collector.`,
+			want: map[string]*data.FieldConfig{
+				"temperature": {Unit: "celsius", Decimals: &decimals, Min: &min, Max: &max, DisplayNameFromDS: "Temperature"},
+			},
+		},
+		{
+			name:    "thresholds",
+			comment: `grafana:{"load": {"thresholds": {"mode": "absolute", "steps": [{"value": null, "color": "green"}, {"value": 80, "color": "red"}]}}}`,
+			want: map[string]*data.FieldConfig{
+				"load": {Thresholds: &data.ThresholdsConfig{
+					Mode:  data.ThresholdsModeAbsolute,
+					Steps: []data.Threshold{data.NewThreshold(math.Inf(-1), "green", ""), data.NewThreshold(80, "red", "")},
+				}},
+			},
+		},
+		{
+			name:    "invalid",
+			comment: `grafana:{"load": "percent"}`,
+			wantErr: "decoder.Decode: json: cannot unmarshal string into Go struct field .load of type data.FieldConfig",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFieldConfigs(tc.comment)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_setFieldConfigs(t *testing.T) {
+	value := data.NewField("value", nil, []float64{1})
+	value.SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias", Unit: "ns"})
+	other := data.NewField("other", nil, []float64{1})
+	other.SetConfig(&data.FieldConfig{Unit: "ns"})
+	frames := data.Frames{nil, data.NewFrame("1", data.NewField("id", nil, []string{"1"}), value, other)}
+
+	setFieldConfigs(frames, map[string]*data.FieldConfig{
+		"value": {DisplayNameFromDS: "Value", Unit: "percent"},
+		"other": {DisplayNameFromDS: "Other"},
+	})
+
+	assert.Nil(t, frames[1].Fields[0].Config)
+	assert.Equal(t, &data.FieldConfig{DisplayNameFromDS: "alias", Unit: "percent"}, frames[1].Fields[1].Config)
+	assert.Equal(t, &data.FieldConfig{DisplayNameFromDS: "Other", Unit: "ns"}, frames[1].Fields[2].Config)
+}
+
+func TestPlugin_ExecQuery_fieldConfigs(t *testing.T) {
+	var comments int
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			result := &cassandra.Result{}
+			result.Add("1", cassandra.Row{
+				Columns: []string{"id", "value", "time"},
+				Fields:  map[string]interface{}{"id": "1", "value": 1.0, "time": time.UnixMilli(1257894000000).UTC()},
+			})
+			return result, nil
+		},
+		onGetTableComment: func(ctx context.Context, keyspace, table string) (string, error) {
+			comments++
+			assert.Equal(t, "keyspace", keyspace)
+			assert.Equal(t, "table", table)
+			if comments > 1 {
+				return "", errors.New("unexpected")
+			}
+			return `grafana:{"value": {"unit": "percent"}}`, nil
+		},
+	}
+
+	p := New(repo, Options{CommentFieldConfigs: true})
+	query := &Query{
+		Keyspace:    "keyspace",
+		Table:       "table",
+		ColumnValue: "value",
+		ColumnID:    "id",
+		ValueID:     "1",
+		ColumnTime:  "time",
+	}
+
+	for i := 0; i < 2; i++ {
+		frames, err := p.ExecQuery(context.TODO(), query)
+		assert.NoError(t, err)
+		assert.Equal(t, &data.FieldConfig{Unit: "percent"}, frames[0].Fields[1].Config)
+	}
+	assert.Equal(t, 1, comments)
+
+	// configs are read again when expired
+	p.fieldConfigs.now = func() time.Time { return time.Now().Add(fieldConfigTTL) }
+	frames, err := p.ExecQuery(context.TODO(), query)
+	assert.NoError(t, err)
+	assert.Nil(t, frames[0].Fields[1].Config)
+	assert.Equal(t, 2, comments)
+}
+
+func TestPlugin_tableFieldConfigs(t *testing.T) {
+	release := make(chan struct{})
+	var comments sync.Map
+	repo := &repositoryMock{
+		onGetTableComment: func(ctx context.Context, keyspace, table string) (string, error) {
+			n, _ := comments.LoadOrStore(table, new(atomic.Int32))
+			switch n.(*atomic.Int32).Add(1) {
+			case 1:
+				if table == "slow" {
+					<-release
+				}
+				return "", context.DeadlineExceeded
+			default:
+				return `grafana:{"value": {"unit": "percent"}}`, nil
+			}
+		},
+	}
+	p := New(repo, Options{CommentFieldConfigs: true})
+	want := map[string]*data.FieldConfig{"value": {Unit: "percent"}}
+
+	// the caller stops waiting for the lookup when it's cancelled
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.Nil(t, p.tableFieldConfigs(ctx, "keyspace", "slow"))
+
+	// other tables are not blocked by the lookup, the timed out lookup isn't cached
+	assert.Nil(t, p.tableFieldConfigs(context.TODO(), "keyspace", "fast"))
+	assert.Equal(t, want, p.tableFieldConfigs(context.TODO(), "keyspace", "fast"))
+	assert.Equal(t, want, p.tableFieldConfigs(context.TODO(), "keyspace", "fast"))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return p.tableFieldConfigs(context.TODO(), "keyspace", "slow") != nil
+	}, time.Second, time.Millisecond)
+
+	n, _ := comments.Load("fast")
+	assert.Equal(t, int32(2), n.(*atomic.Int32).Load())
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"gopkg.in/inf.v0"
+)
+
+// Filter operators supported in strict queries.
+const (
+	filterEqual    = "="
+	filterIn       = "IN"
+	filterLess     = "<"
+	filterGreater  = ">"
+	filterContains = "CONTAINS"
+)
+
+// Filter is an additional WHERE condition of a strict query.
+type Filter struct {
+	Column   string
+	Operator string
+	// Value is a string presentation of a compared value,
+	// IN operator expects comma separated list of values.
+	Value string
+}
+
+// filterClause renders filters as WHERE conditions with positional parameters.
+func filterClause(filters []Filter) string {
+	var clause strings.Builder
+	for _, f := range filters {
+		fmt.Fprintf(&clause, " AND %s %s ?", f.Column, strings.ToUpper(f.Operator))
+	}
+
+	return clause.String()
+}
+
+// columnName returns the name of the column referenced by the CQL identifier. Unquoted
+// identifiers are case-insensitive, cassandra keeps them in lower case, quoted ones are case-sensitive.
+func columnName(identifier string) string {
+	if len(identifier) >= 2 && strings.HasPrefix(identifier, `"`) && strings.HasSuffix(identifier, `"`) {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	}
+
+	return strings.ToLower(identifier)
+}
+
+// makeFilterValues validates filters against the table columns and converts
+// filter values to the column types, so they can be bound to the statement.
+func makeFilterValues(filters []Filter, columns map[string]cassandra.ColumnType) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(filters))
+	for _, f := range filters {
+		column, ok := columns[columnName(f.Column)]
+		if !ok {
+			return nil, fmt.Errorf("filter column %s does not exist", f.Column)
+		}
+
+		var (
+			val interface{}
+			err error
+		)
+		switch op := strings.ToUpper(f.Operator); op {
+		case filterEqual, filterLess, filterGreater:
+			if column.IsCollection() {
+				return nil, fmt.Errorf("operator %s is not supported for %s column %s", op, column.Type, f.Column)
+			}
+			val, err = parseFilterValue(column.Type, f.Value)
+		case filterIn:
+			if column.IsCollection() {
+				return nil, fmt.Errorf("operator %s is not supported for %s column %s", op, column.Type, f.Column)
+			}
+			var list []interface{}
+			for _, item := range strings.Split(f.Value, ",") {
+				var itemVal interface{}
+				itemVal, err = parseFilterValue(column.Type, strings.TrimSpace(item))
+				if err != nil {
+					break
+				}
+				list = append(list, itemVal)
+			}
+			val = list
+		case filterContains:
+			if !column.IsCollection() {
+				return nil, fmt.Errorf("operator %s requires a collection column, %s is %s", op, f.Column, column.Type)
+			}
+			val, err = parseFilterValue(column.Elem, f.Value)
+		default:
+			return nil, fmt.Errorf("unsupported filter operator: %s", f.Operator)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("filter %s value: %w", f.Column, err)
+		}
+
+		values = append(values, val)
+	}
+
+	return values, nil
+}
+
+// parseFilterValue converts string value to a type which can be marshalled to the CQL type.
+func parseFilterValue(cqlType, s string) (interface{}, error) {
+	switch cqlType {
+	case "tinyint", "smallint", "int", "bigint", "counter", "varint":
+		return strconv.ParseInt(s, 10, 64)
+	case "float":
+		val, err := strconv.ParseFloat(s, 32)
+		return float32(val), err
+	case "double":
+		return strconv.ParseFloat(s, 64)
+	case "decimal":
+		val, ok := new(inf.Dec).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal %q", s)
+		}
+		return val, nil
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "timestamp":
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.UnixMilli(ms).UTC(), nil
+		}
+		return time.Parse(time.RFC3339, s)
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/inf.v0"
+)
+
+func Test_makeFilterValues(t *testing.T) {
+	columns := map[string]cassandra.ColumnType{
+		"region":  {Type: "text"},
+		"level":   {Type: "int"},
+		"ratio":   {Type: "double"},
+		"price":   {Type: "decimal"},
+		"enabled": {Type: "boolean"},
+		"since":   {Type: "timestamp"},
+		"tags":    {Type: "set", Elem: "text"},
+		"Zone":    {Type: "text"},
+	}
+
+	testCases := []struct {
+		name    string
+		filters []Filter
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name: "typed values",
+			filters: []Filter{
+				{Column: "region", Operator: "=", Value: "eu"},
+				{Column: "level", Operator: ">", Value: "3"},
+				{Column: "ratio", Operator: "<", Value: "0.5"},
+				{Column: "price", Operator: "=", Value: "1.25"},
+				{Column: "enabled", Operator: "=", Value: "true"},
+				{Column: "since", Operator: ">", Value: "1257894000000"},
+			},
+			want: []interface{}{"eu", int64(3), 0.5, inf.NewDec(125, 2), true, time.UnixMilli(1257894000000).UTC()},
+		},
+		{
+			name:    "in",
+			filters: []Filter{{Column: "level", Operator: "in", Value: "1, 2,3"}},
+			want:    []interface{}{[]interface{}{int64(1), int64(2), int64(3)}},
+		},
+		{
+			name:    "contains",
+			filters: []Filter{{Column: "tags", Operator: "CONTAINS", Value: "indoor"}},
+			want:    []interface{}{"indoor"},
+		},
+		{
+			name: "identifier case",
+			filters: []Filter{
+				{Column: "Region", Operator: "=", Value: "eu"},
+				{Column: `"Zone"`, Operator: "=", Value: "a"},
+			},
+			want: []interface{}{"eu", "a"},
+		},
+		{
+			name:    "case-sensitive quoted identifier",
+			filters: []Filter{{Column: `"Region"`, Operator: "=", Value: "eu"}},
+			wantErr: `filter column "Region" does not exist`,
+		},
+		{
+			name:    "unknown column",
+			filters: []Filter{{Column: "city", Operator: "=", Value: "Paris"}},
+			wantErr: "filter column city does not exist",
+		},
+		{
+			name:    "unsupported operator",
+			filters: []Filter{{Column: "level", Operator: "!=", Value: "1"}},
+			wantErr: "unsupported filter operator: !=",
+		},
+		{
+			name:    "contains on non-collection",
+			filters: []Filter{{Column: "region", Operator: "contains", Value: "eu"}},
+			wantErr: "operator CONTAINS requires a collection column, region is text",
+		},
+		{
+			name:    "compare collection",
+			filters: []Filter{{Column: "tags", Operator: "=", Value: "indoor"}},
+			wantErr: "operator = is not supported for set column tags",
+		},
+		{
+			name:    "invalid value",
+			filters: []Filter{{Column: "level", Operator: "IN", Value: "1,two"}},
+			wantErr: `filter level value: strconv.ParseInt: parsing "two": invalid syntax`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := makeFilterValues(tc.filters, columns)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, values)
+		})
+	}
+}
+
+func TestPlugin_ExecQuery_filters(t *testing.T) {
+	var (
+		statement string
+		bound     []interface{}
+	)
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			statement, bound = query, values
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{}}, nil
+		},
+		onGetColumnTypes: func(keyspace, table string) (map[string]cassandra.ColumnType, error) {
+			return map[string]cassandra.ColumnType{"region": {Type: "text"}}, nil
+		},
+	}
+
+	from, to := time.UnixMilli(1257894000000), time.UnixMilli(1257894010000)
+	p := &Plugin{repo: repo}
+	_, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:    "keyspace",
+		Table:       "table",
+		ColumnValue: "value",
+		ColumnID:    "id",
+		ValueID:     "1",
+		ColumnTime:  "time",
+		TimeFrom:    from,
+		TimeTo:      to,
+		Filters:     []Filter{{Column: "region", Operator: "=", Value: "eu"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, value, time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? AND region = ?", statement)
+	assert.Equal(t, []interface{}{[]string{"1"}, cassandra.Timestamp(from), cassandra.Timestamp(to), "eu"}, bound)
+
+	_, err = p.ExecQuery(context.TODO(), &Query{
+		Keyspace: "keyspace",
+		Table:    "table",
+		Filters:  []Filter{{Column: "city", Operator: "=", Value: "Paris"}},
+	})
+	assert.EqualError(t, err, "query processing: makeFilterValues: filter column city does not exist")
+}
@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Limits of the rows kept for incremental refresh: the number of queries
+// and the approximate total size of their rows.
+const (
+	maxIncrementalSeries = 100
+	maxIncrementalBytes  = 64 << 20
+)
+
+// seriesEntry is the rows of a strict query fetched for the time range.
+type seriesEntry struct {
+	key      string
+	from, to time.Time
+	result   *cassandra.Result
+	size     int
+}
+
+// seriesStore keeps rows of the recently executed incremental queries,
+// least recently used entries are evicted when the store is full.
+type seriesStore struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int
+}
+
+func newSeriesStore() *seriesStore {
+	return &seriesStore{
+		maxBytes: maxIncrementalBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (s *seriesStore) get(key string) (*seriesEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+
+	return elem.Value.(*seriesEntry), true
+}
+
+// put stores the entry, unless its rows alone exceed the size limit.
+func (s *seriesStore) put(entry *seriesEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(entry.key)
+	for _, rows := range entry.result.Rows {
+		for _, row := range rows {
+			entry.size += row.Size()
+		}
+	}
+	if entry.size > s.maxBytes {
+		return
+	}
+	s.entries[entry.key] = s.lru.PushFront(entry)
+	s.bytes += entry.size
+
+	for s.lru.Len() > maxIncrementalSeries || s.bytes > s.maxBytes {
+		s.removeLocked(s.lru.Back().Value.(*seriesEntry).key)
+	}
+}
+
+func (s *seriesStore) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(key)
+}
+
+func (s *seriesStore) removeLocked(key string) {
+	if elem, ok := s.entries[key]; ok {
+		s.lru.Remove(elem)
+		delete(s.entries, key)
+		s.bytes -= elem.Value.(*seriesEntry).size
+	}
+}
+
+// execIncrementalMetricQuery executes strict query reusing the rows returned by the
+// previous execution of the same query. When the new time range continues the previous
+// one, only the tail of the range is fetched, the rest is taken from memory and the rows
+// older than the new range start are dropped. Otherwise, the whole range is fetched.
+// The tail starts at the earliest of the latest rows of the series, so rows
+// written late, but newer than the latest row of their series, are not missed.
+// Older rows written later are not seen until the next full query.
+func (p *Plugin) execIncrementalMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	key := q.fingerprint()
+	prev, ok := p.series.get(key)
+	if ok && (q.TimeFrom.Before(prev.from) || q.TimeFrom.After(prev.to) || q.TimeTo.Before(prev.to)) {
+		ok = false
+	}
+
+	// cached results are shared by the time ranges of the cache TTL and could miss the tail rows
+	fetch := *q
+	fetch.NoCache = true
+	if ok {
+		fetch.TimeFrom = tailStart(prev, q.ColumnTime)
+		if fetch.TimeFrom.Before(q.TimeFrom) {
+			fetch.TimeFrom = q.TimeFrom
+		}
+		if !q.TimeTo.After(prev.to) {
+			// the range end isn't moved, all the rows are taken from memory
+			fetch.TimeFrom = prev.to.Add(time.Millisecond)
+		}
+	}
+
+	result := &cassandra.Result{Rows: make(map[string][]cassandra.Row)}
+	if !fetch.TimeFrom.After(fetch.TimeTo) {
+		var err error
+		result, err = p.selectStrictRows(ctx, &fetch, fetch.BuildStatement())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ok {
+		merged := mergeRows(prev.result, result, q.ColumnTime, q.TimeFrom, fetch.TimeFrom)
+		merged.Notices = result.Notices
+		result = merged
+		sortRowsByTime(result, q.ColumnTime)
+	}
+
+	if len(result.Notices) == 0 {
+		p.series.put(&seriesEntry{key: key, from: q.TimeFrom, to: q.TimeTo, result: result})
+	} else {
+		// truncated result can't be continued
+		p.series.remove(key)
+	}
+
+	return makeDataFrames(q, copyResult(result))
+}
+
+// tailStart returns the earliest of the latest row times of the series.
+// The end of the previous range is returned when it has no rows.
+func tailStart(prev *seriesEntry, timeColumn string) time.Time {
+	start := prev.to
+	for _, rows := range prev.result.Rows {
+		var latest time.Time
+		for _, row := range rows {
+			if t, ok := row.Fields[timeColumn].(time.Time); ok && t.After(latest) {
+				latest = t
+			}
+		}
+		if !latest.IsZero() && latest.Before(start) {
+			start = latest
+		}
+	}
+
+	return start
+}
+
+// mergeRows returns rows of prev not older than from and older than tail
+// followed by rows of next, which are fetched from tail.
+func mergeRows(prev, next *cassandra.Result, timeColumn string, from, tail time.Time) *cassandra.Result {
+	merged := &cassandra.Result{Rows: make(map[string][]cassandra.Row, len(prev.Rows))}
+	for _, id := range prev.OrderedIDs() {
+		for _, row := range prev.Rows[id] {
+			if t, _ := row.Fields[timeColumn].(time.Time); !t.Before(from) && t.Before(tail) {
+				merged.Add(id, row)
+			}
+		}
+	}
+	for _, id := range next.OrderedIDs() {
+		for _, row := range next.Rows[id] {
+			if t, _ := row.Fields[timeColumn].(time.Time); !t.Before(tail) {
+				merged.Add(id, row)
+			}
+		}
+	}
+
+	return merged
+}
+
+// fingerprint returns a hash of the query fields except the time range.
+func (q *Query) fingerprint() string {
+	fq := *q
+	fq.TimeFrom, fq.TimeTo = time.Time{}, time.Time{}
+	fq.Interval, fq.MaxDataPoints = 0, 0
+	fq.NoCache = false
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%#v", fq)))
+
+	return hex.EncodeToString(hash[:])
+}
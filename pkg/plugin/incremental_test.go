@@ -0,0 +1,208 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_incremental(t *testing.T) {
+	minute := func(m int) time.Time {
+		return time.Date(2024, 1, 1, 0, m, 0, 0, time.UTC)
+	}
+
+	var ranges [][2]time.Time
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			assert.True(t, opts.NoCache)
+			from, to := time.Time(values[1].(cassandra.Timestamp)), time.Time(values[2].(cassandra.Timestamp))
+			ranges = append(ranges, [2]time.Time{from, to})
+
+			// a row every minute
+			var rows []cassandra.Row
+			for m := from.Truncate(time.Minute); !m.After(to); m = m.Add(time.Minute) {
+				if m.Before(from) {
+					continue
+				}
+				rows = append(rows, cassandra.Row{
+					Columns: []string{"id", "value", "time"},
+					Fields:  map[string]interface{}{"id": "1", "value": float64(m.Minute()), "time": m},
+				})
+			}
+
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{"1": rows}}, nil
+		},
+	}
+
+	p := New(repo, Options{})
+	query := func(from, to time.Time) []float64 {
+		frames, err := p.ExecQuery(context.TODO(), &Query{
+			Keyspace:    "keyspace",
+			Table:       "table",
+			ColumnValue: "value",
+			ColumnID:    "id",
+			ValueID:     "1",
+			ColumnTime:  "time",
+			TimeFrom:    from,
+			TimeTo:      to,
+			Incremental: true,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, frames, 1)
+
+		var values []float64
+		field := frames[0].Fields[1]
+		for i := 0; i < field.Len(); i++ {
+			values = append(values, field.At(i).(float64))
+		}
+		return values
+	}
+
+	// full query
+	assert.Equal(t, []float64{0, 1, 2, 3}, query(minute(0), minute(3)))
+	// shifted range fetches only the rows from the latest one
+	assert.Equal(t, []float64{2, 3, 4, 5}, query(minute(2), minute(5)))
+	// range which isn't moved doesn't query the database
+	assert.Equal(t, []float64{2, 3, 4, 5}, query(minute(2), minute(5)))
+	// non-contiguous range is fetched fully
+	assert.Equal(t, []float64{10, 11}, query(minute(10), minute(11)))
+
+	assert.Equal(t, [][2]time.Time{
+		{minute(0), minute(3)},
+		{minute(3), minute(5)},
+		{minute(10), minute(11)},
+	}, ranges)
+}
+
+func TestPlugin_ExecQuery_incrementalTruncated(t *testing.T) {
+	var calls int
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			calls++
+			return &cassandra.Result{
+				Rows: map[string][]cassandra.Row{"1": {{
+					Columns: []string{"id", "value", "time"},
+					Fields:  map[string]interface{}{"id": "1", "value": 1.0, "time": time.Time(values[1].(cassandra.Timestamp))},
+				}}},
+				Notices: []string{"Result is truncated: limit of 1 rows reached"},
+			}, nil
+		},
+	}
+
+	p := New(repo, Options{})
+	for i := 0; i < 2; i++ {
+		_, err := p.ExecQuery(context.TODO(), &Query{
+			Keyspace:    "keyspace",
+			Table:       "table",
+			ColumnValue: "value",
+			ColumnID:    "id",
+			ValueID:     "1",
+			ColumnTime:  "time",
+			TimeFrom:    time.UnixMilli(1257894000000).UTC(),
+			TimeTo:      time.UnixMilli(1257894060000).UTC(),
+			Incremental: true,
+		})
+		assert.NoError(t, err)
+	}
+
+	// truncated results are not reused
+	assert.Equal(t, 2, calls)
+}
+
+func TestPlugin_ExecQuery_incrementalLateRows(t *testing.T) {
+	minute := func(m int) time.Time {
+		return time.Date(2024, 1, 1, 0, m, 0, 0, time.UTC)
+	}
+	row := func(id string, m int) cassandra.Row {
+		return cassandra.Row{
+			Columns: []string{"id", "value", "time"},
+			Fields:  map[string]interface{}{"id": id, "value": float64(m), "time": minute(m)},
+		}
+	}
+
+	var froms []time.Time
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			froms = append(froms, time.Time(values[1].(cassandra.Timestamp)))
+			if len(froms) == 1 {
+				// the row of the second series at the minute 2 is not written yet
+				return &cassandra.Result{Rows: map[string][]cassandra.Row{
+					"1": {row("1", 1), row("1", 2), row("1", 3)},
+					"2": {row("2", 1)},
+				}}, nil
+			}
+			return &cassandra.Result{Rows: map[string][]cassandra.Row{
+				"1": {row("1", 1), row("1", 2), row("1", 3), row("1", 4)},
+				"2": {row("2", 1), row("2", 2), row("2", 4)},
+			}}, nil
+		},
+	}
+
+	p := New(repo, Options{})
+	var frames data.Frames
+	for _, to := range []int{3, 4} {
+		var err error
+		frames, err = p.ExecQuery(context.TODO(), &Query{
+			Keyspace:    "keyspace",
+			Table:       "table",
+			ColumnValue: "value",
+			ColumnID:    "id",
+			ValueID:     "1,2",
+			ColumnTime:  "time",
+			TimeFrom:    minute(0),
+			TimeTo:      minute(to),
+			Incremental: true,
+		})
+		assert.NoError(t, err)
+	}
+
+	// the tail starts at the latest row of the second series
+	assert.Equal(t, []time.Time{minute(0), minute(1)}, froms)
+	assert.Len(t, frames, 2)
+	assert.Equal(t, 4, frames[0].Rows())
+	assert.Equal(t, 3, frames[1].Rows())
+}
+
+func Test_seriesStore_maxBytes(t *testing.T) {
+	entry := func(key string, values ...string) *seriesEntry {
+		var rows []cassandra.Row
+		for _, val := range values {
+			rows = append(rows, cassandra.Row{Columns: []string{"value"}, Fields: map[string]interface{}{"value": val}})
+		}
+		return &seriesEntry{key: key, result: &cassandra.Result{Rows: map[string][]cassandra.Row{"1": rows}}}
+	}
+
+	store := newSeriesStore()
+	store.maxBytes = 10
+	store.put(entry("a", "1234"))
+	store.put(entry("b", "1234"))
+	assert.Equal(t, 8, store.bytes)
+
+	// the least recently used entry is evicted to fit the new one
+	_, _ = store.get("a")
+	store.put(entry("c", "1234"))
+	_, ok := store.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 8, store.bytes)
+
+	// an entry larger than the store is not kept
+	store.put(entry("a", "12345678901"))
+	_, ok = store.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 4, store.bytes)
+}
+
+func TestQuery_fingerprint(t *testing.T) {
+	q := Query{Keyspace: "keyspace", Table: "table", ValueID: "1", TimeFrom: time.UnixMilli(1000), TimeTo: time.UnixMilli(2000)}
+	shifted := q
+	shifted.TimeFrom, shifted.TimeTo = time.UnixMilli(3000), time.UnixMilli(4000)
+	other := q
+	other.ValueID = "2"
+
+	assert.Equal(t, q.fingerprint(), shifted.fingerprint())
+	assert.NotEqual(t, q.fingerprint(), other.fingerprint())
+}
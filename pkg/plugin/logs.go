@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// formatLogs is a query result format of grafana log lines.
+const formatLogs = "logs"
+
+// defaultLogsPageSize is a number of log lines returned at once when the query page size is not set.
+const defaultLogsPageSize = 1000
+
+// defaultLogBodyColumns are the column names used as the log line body when the body column is not set.
+var defaultLogBodyColumns = []string{"message", "body", "line"}
+
+// LogsMeta is a custom metadata of the logs frame.
+type LogsMeta struct {
+	// PageState is a base64 encoded paging state of the next page,
+	// it is empty when there are no more rows.
+	PageState string `json:"pageState,omitempty"`
+}
+
+// execLogsQuery executes a single page of a raw query and transforms rows to log lines.
+// Query is expected to return the newest rows first, so the next page contains older lines.
+func (p *Plugin) execLogsQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	if !q.RawQuery {
+		return nil, errors.New("logs format requires a raw query")
+	}
+
+	pageState, err := base64.StdEncoding.DecodeString(q.PageState)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page state: %w", err)
+	}
+	if len(pageState) == 0 {
+		pageState = nil
+	}
+
+	opts := q.selectOptions()
+	if opts.PageSize == 0 {
+		opts.PageSize = defaultLogsPageSize
+	}
+
+	var rows []cassandra.Row
+	notices, nextPageState, err := p.repo.SelectPageFunc(ctx, opts, pageState, func(_ string, row cassandra.Row) error {
+		rows = append(rows, row)
+		return nil
+	}, q.Target, q.Values...)
+	if err != nil {
+		return nil, fmt.Errorf("repo.SelectPageFunc: %w", err)
+	}
+	if len(rows) == 0 {
+		return appendNotices(nil, notices), nil
+	}
+
+	frame, err := makeLogsFrame(rows, q.LogBodyColumn, q.LogLevelColumn)
+	if err != nil {
+		return nil, fmt.Errorf("makeLogsFrame: %w", err)
+	}
+	frame.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeLogLines,
+		TypeVersion:            data.FrameTypeVersion{0, 0},
+		PreferredVisualization: data.VisTypeLogs,
+	}
+	if len(nextPageState) > 0 {
+		frame.Meta.Custom = LogsMeta{PageState: base64.StdEncoding.EncodeToString(nextPageState)}
+	}
+
+	return appendNotices(data.Frames{frame}, notices), nil
+}
+
+// makeLogsFrame creates log lines frame with timestamp, body, severity and labels fields.
+// The first timestamp column is the line time. Unless bodyColumn is set, the body is
+// a column named like message, body or line, or the first text column. Entries of map
+// columns and values of the other columns become labels. Rows without time are skipped.
+func makeLogsFrame(rows []cassandra.Row, bodyColumn, levelColumn string) (*data.Frame, error) {
+	columns := rows[0].Columns
+	hasColumn := func(name string) bool {
+		for _, colName := range columns {
+			if colName == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	timeColumn, err := firstTimeColumn(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if bodyColumn == "" {
+		for _, name := range defaultLogBodyColumns {
+			if hasColumn(name) {
+				bodyColumn = name
+				break
+			}
+		}
+	}
+	if bodyColumn == "" {
+		for _, colName := range columns {
+			_, isString := rows[0].Fields[colName].(string)
+			fieldType := rows[0].Types[colName]
+			isString = isString || fieldType == data.FieldTypeString || fieldType == data.FieldTypeNullableString
+			if colName != levelColumn && isString && rows[0].Collections[colName] == nil {
+				bodyColumn = colName
+				break
+			}
+		}
+	}
+	if bodyColumn == "" || !hasColumn(bodyColumn) {
+		return nil, fmt.Errorf("body column %q not found", bodyColumn)
+	}
+	if levelColumn != "" && !hasColumn(levelColumn) {
+		return nil, fmt.Errorf("level column %q not found", levelColumn)
+	}
+
+	var (
+		timestamps = make([]time.Time, 0, len(rows))
+		bodies     = make([]string, 0, len(rows))
+		severities = make([]string, 0, len(rows))
+		labels     = make([]json.RawMessage, 0, len(rows))
+	)
+	for _, row := range rows {
+		t, ok := row.Fields[timeColumn].(time.Time)
+		if !ok {
+			continue
+		}
+		timestamps = append(timestamps, t)
+		bodies = append(bodies, textValue(row.Fields[bodyColumn]))
+		severities = append(severities, textValue(row.Fields[levelColumn]))
+
+		rowLabels := data.Labels{}
+		for _, colName := range row.Columns {
+			if colName == timeColumn || colName == bodyColumn || colName == levelColumn {
+				continue
+			}
+			if m, ok := row.Collections[colName].(map[string]interface{}); ok {
+				for k, v := range m {
+					rowLabels[k] = fmt.Sprintf("%v", v)
+				}
+				continue
+			}
+			if val := row.Fields[colName]; val != nil {
+				rowLabels[colName] = fmt.Sprintf("%v", val)
+			}
+		}
+		labelsJSON, err := json.Marshal(rowLabels)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		labels = append(labels, labelsJSON)
+	}
+
+	frame := data.NewFrame("logs",
+		data.NewField("timestamp", nil, timestamps),
+		data.NewField("body", nil, bodies),
+	)
+	if levelColumn != "" {
+		frame.Fields = append(frame.Fields, data.NewField("severity", nil, severities))
+	}
+	frame.Fields = append(frame.Fields, data.NewField("labels", nil, labels))
+
+	return frame, nil
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_logs(t *testing.T) {
+	t1 := time.UnixMilli(1257894001000).UTC()
+	t2 := time.UnixMilli(1257894000000).UTC()
+	columns := []string{"app_id", "ts", "level", "message", "attrs"}
+	rows := []cassandra.Row{
+		{
+			Columns:     columns,
+			Fields:      map[string]interface{}{"app_id": "api", "ts": t1, "level": "error", "message": "request failed", "attrs": `{"host":"a"}`},
+			Collections: map[string]interface{}{"attrs": map[string]interface{}{"host": "a"}},
+		},
+		{
+			Columns: columns,
+			Fields:  map[string]interface{}{"app_id": "api", "ts": t2, "level": "info", "message": "started", "attrs": nil},
+		},
+	}
+
+	var pageStates [][]byte
+	repo := &repositoryMock{
+		onSelectPageFunc: func(ctx context.Context, opts cassandra.SelectOptions, pageState []byte, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, []byte, error) {
+			assert.Equal(t, defaultLogsPageSize, opts.PageSize)
+			pageStates = append(pageStates, pageState)
+			for _, row := range rows {
+				if err := fn("api", row); err != nil {
+					return nil, nil, err
+				}
+			}
+			return nil, []byte{1, 2, 3}, nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	frames, err := p.ExecQuery(context.TODO(), &Query{
+		RawQuery:       true,
+		Target:         "SELECT app_id, ts, level, message, attrs FROM ks.logs WHERE app_id = 'api' ORDER BY ts DESC",
+		Format:         "logs",
+		LogLevelColumn: "level",
+	})
+	assert.NoError(t, err)
+
+	want := data.NewFrame("logs",
+		data.NewField("timestamp", nil, []time.Time{t1, t2}),
+		data.NewField("body", nil, []string{"request failed", "started"}),
+		data.NewField("severity", nil, []string{"error", "info"}),
+		data.NewField("labels", nil, []json.RawMessage{
+			json.RawMessage(`{"app_id":"api","host":"a"}`),
+			json.RawMessage(`{"app_id":"api"}`),
+		}),
+	)
+	want.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeLogLines,
+		TypeVersion:            data.FrameTypeVersion{0, 0},
+		PreferredVisualization: data.VisTypeLogs,
+		Custom:                 LogsMeta{PageState: "AQID"},
+	}
+	assert.Equal(t, data.Frames{want}, frames)
+
+	// next page
+	_, err = p.ExecQuery(context.TODO(), &Query{
+		RawQuery:  true,
+		Target:    "SELECT app_id, ts, level, message, attrs FROM ks.logs WHERE app_id = 'api' ORDER BY ts DESC",
+		Format:    "logs",
+		PageState: "AQID",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{nil, {1, 2, 3}}, pageStates)
+}
+
+func Test_makeLogsFrame(t *testing.T) {
+	ts := time.UnixMilli(1257894000000).UTC()
+
+	testCases := []struct {
+		name        string
+		rows        []cassandra.Row
+		bodyColumn  string
+		levelColumn string
+		wantBody    []string
+		wantErr     string
+	}{
+		{
+			name: "first text column",
+			rows: []cassandra.Row{{
+				Columns: []string{"ts", "line_text", "code"},
+				Fields:  map[string]interface{}{"ts": ts, "line_text": "hello", "code": int64(1)},
+			}},
+			wantBody: []string{"hello"},
+		},
+		{
+			name: "chosen body column",
+			rows: []cassandra.Row{{
+				Columns: []string{"ts", "message", "code"},
+				Fields:  map[string]interface{}{"ts": ts, "message": "hello", "code": int64(1)},
+			}},
+			bodyColumn: "code",
+			wantBody:   []string{"1"},
+		},
+		{
+			name: "no time column",
+			rows: []cassandra.Row{{
+				Columns: []string{"message"},
+				Fields:  map[string]interface{}{"message": "hello"},
+			}},
+			wantErr: "timestamp column is required",
+		},
+		{
+			name: "unknown level column",
+			rows: []cassandra.Row{{
+				Columns: []string{"ts", "message"},
+				Fields:  map[string]interface{}{"ts": ts, "message": "hello"},
+			}},
+			levelColumn: "level",
+			wantErr:     `level column "level" not found`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := makeLogsFrame(tc.rows, tc.bodyColumn, tc.levelColumn)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, data.NewField("body", nil, tc.wantBody), frame.Fields[1])
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxParallelQueries limits the number of concurrent per-ID queries of a single strict query.
+const maxParallelQueries = 16
+
+// selectStrict executes strict query statement. IDs must be bound to the first marker.
+// When the query is parallel, the statement is executed once per ID and results are merged.
+func (p *Plugin) selectStrict(ctx context.Context, q *Query, statement string, values []interface{}) (*cassandra.Result, error) {
+	if !q.Parallel {
+		return p.repo.Select(ctx, q.selectOptions(), statement, values...)
+	}
+
+	var mu sync.Mutex
+	opts := parallelOptions(q, values)
+	idResults := make(map[interface{}]*cassandra.Result)
+	notices, err := fanOut(ctx, values, func(ctx context.Context, values []interface{}) ([]string, error) {
+		idResult, err := p.repo.Select(ctx, opts, statement, values...)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		idResults[values[0]] = idResult
+		mu.Unlock()
+
+		return idResult.Notices, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// results are merged in the order of requested IDs, not in the order of completion
+	result := &cassandra.Result{Rows: make(map[string][]cassandra.Row), Notices: notices}
+	for _, id := range values[0].([]string) {
+		idResult, ok := idResults[id]
+		if !ok {
+			continue
+		}
+		for _, resultID := range idResult.OrderedIDs() {
+			for _, row := range idResult.Rows[resultID] {
+				result.Add(resultID, row)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// selectStrictFunc is the same as selectStrict, but rows are processed with fn
+// while they are fetched. Calls of fn are serialized for the parallel query.
+func (p *Plugin) selectStrictFunc(ctx context.Context, q *Query, fn cassandra.RowFunc, statement string, values []interface{}) ([]string, error) {
+	if !q.Parallel {
+		return p.repo.SelectFunc(ctx, q.selectOptions(), fn, statement, values...)
+	}
+
+	var mu sync.Mutex
+	syncFn := func(id string, row cassandra.Row) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return fn(id, row)
+	}
+
+	opts := parallelOptions(q, values)
+
+	return fanOut(ctx, values, func(ctx context.Context, values []interface{}) ([]string, error) {
+		return p.repo.SelectFunc(ctx, opts, syncFn, statement, values...)
+	})
+}
+
+// parallelOptions returns select options of the per-ID queries, which share the query limits.
+func parallelOptions(q *Query, values []interface{}) cassandra.SelectOptions {
+	opts := q.selectOptions()
+	if ids, ok := values[0].([]string); ok {
+		opts.Split = len(ids)
+	}
+
+	return opts
+}
+
+// fanOut calls query concurrently for every ID of the IDs list, which is the first of values.
+// The list is replaced with a single ID for each call. When any of the calls fails,
+// the others are cancelled. Returns unique notices of all calls.
+func fanOut(ctx context.Context, values []interface{}, query func(ctx context.Context, values []interface{}) ([]string, error)) ([]string, error) {
+	ids, ok := values[0].([]string)
+	if !ok {
+		return nil, fmt.Errorf("IDs have unsupported type %T", values[0])
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(maxParallelQueries)
+
+	notices := make([][]string, len(ids))
+	for i, id := range ids {
+		idValues := append([]interface{}{id}, values[1:]...)
+		group.Go(func() error {
+			idNotices, err := query(groupCtx, idValues)
+			if err != nil {
+				return fmt.Errorf("query of ID %s: %w", id, err)
+			}
+			notices[i] = idNotices
+
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	var merged []string
+	seen := make(map[string]bool)
+	for _, idNotices := range notices {
+		for _, notice := range idNotices {
+			if !seen[notice] {
+				seen[notice] = true
+				merged = append(merged, notice)
+			}
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_parallel(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		statements []string
+		ids        []string
+	)
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			id := values[0].(string)
+			assert.Equal(t, cassandra.SelectOptions{MaxRows: 30, Split: 3}, opts)
+
+			mu.Lock()
+			statements = append(statements, query)
+			ids = append(ids, id)
+			mu.Unlock()
+
+			return &cassandra.Result{
+				Rows: map[string][]cassandra.Row{
+					id: {{
+						Columns: []string{"id", "value", "time"},
+						Fields:  map[string]interface{}{"id": id, "value": 1.0, "time": time.UnixMilli(1257894000000).UTC()},
+					}},
+				},
+				Notices: []string{"Result is truncated: limit of 1 rows reached"},
+			}, nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	frames, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:    "keyspace",
+		Table:       "table",
+		ColumnValue: "value",
+		ColumnID:    "id",
+		ValueID:     "1, 2, 3",
+		ColumnTime:  "time",
+		Parallel:    true,
+		MaxRows:     30,
+	})
+
+	assert.NoError(t, err)
+	sort.Strings(ids)
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+	for _, statement := range statements {
+		assert.Equal(t, "SELECT id, value, time FROM keyspace.table WHERE id = ? AND time >= ? AND time <= ?", statement)
+	}
+	assert.Len(t, frames, 3)
+	// frames follow the order of requested IDs regardless of completion order
+	for i, id := range []string{"1", "2", "3"} {
+		assert.Equal(t, id, frames[i].Name)
+	}
+
+	var notices []data.Notice
+	for _, frame := range frames {
+		if frame.Meta != nil {
+			notices = append(notices, frame.Meta.Notices...)
+		}
+	}
+	assert.Equal(t, []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 1 rows reached"}}, notices)
+}
+
+func TestPlugin_ExecQuery_parallelError(t *testing.T) {
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			if values[0] == "2" {
+				return nil, errors.New("timeout")
+			}
+			// other queries are cancelled
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	_, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:    "keyspace",
+		Table:       "table",
+		ColumnValue: "value",
+		ColumnID:    "id",
+		ValueID:     "1,2,3",
+		ColumnTime:  "time",
+		Parallel:    true,
+	})
+
+	assert.EqualError(t, err, "query processing: selectStrict: query of ID 2: timeout")
+}
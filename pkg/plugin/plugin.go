@@ -2,39 +2,108 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// versionTimeout limits the cluster version lookup.
+	versionTimeout = 10 * time.Second
+	// versionRetryInterval is the time a failed cluster version lookup is not retried.
+	versionRetryInterval = time.Minute
 )
 
 var aliasFormatRegexp = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
 
 type repository interface {
-	Select(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error)
+	// Select returns rows of the query. Rows could be shared with the other callers
+	// by the result cache, so maps of the rows must not be modified.
+	Select(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error)
+	SelectFunc(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error)
+	SelectPageFunc(ctx context.Context, opts cassandra.SelectOptions, pageState []byte, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, []byte, error)
 	GetKeyspaces(ctx context.Context) ([]string, error)
 	GetTables(keyspace string) ([]string, error)
 	GetColumns(keyspace, table, needType string) ([]string, error)
+	GetColumnTypes(keyspace, table string) (map[string]cassandra.ColumnType, error)
+	GetVersion(ctx context.Context) (string, error)
+	GetTraceSpans(ctx context.Context, keyspace, table string, traceID []byte) ([]cassandra.Span, error)
+	GetTableComment(ctx context.Context, keyspace, table string) (string, error)
 	Ping(ctx context.Context) error
 	Close()
 }
 
+// defaultConcurrentQueries is used when the concurrent queries limit is not set.
+const defaultConcurrentQueries = 5
+
+// Options contains datasource instance settings of the plugin.
+type Options struct {
+	// ConcurrentQueries limits the number of queries executed at once by the datasource instance.
+	ConcurrentQueries int
+	// CacheTTL enables caching of the query results for the given time.
+	CacheTTL time.Duration
+	// CacheSize is a maximum number of cached results.
+	CacheSize int
+	// CommentFieldConfigs enables field configs of the strict queries
+	// defined in the table comment, see parseFieldConfigs.
+	CommentFieldConfigs bool
+}
+
 // Plugin represents grafana datasource plugin.
 type Plugin struct {
 	repo repository
+	opts Options
+
+	// version is the cluster release version, fetched once on demand. A failed
+	// lookup is not retried until versionRetry, so queries don't wait for it.
+	versionMu    sync.Mutex
+	version      string
+	versionRetry time.Time
+	versionGroup singleflight.Group
+
+	// series keeps rows of the incremental queries between refreshes.
+	series *seriesStore
+
+	// fieldConfigs keeps field configs parsed from the table comments.
+	fieldConfigs *fieldConfigStore
+
+	// querySlots is a semaphore of ConcurrentQueries size shared by all data requests.
+	querySlots chan struct{}
 }
 
 // New returns configured Plugin.
-func New(repo repository) *Plugin {
+func New(repo repository, opts Options) *Plugin {
+	if opts.ConcurrentQueries <= 0 {
+		opts.ConcurrentQueries = defaultConcurrentQueries
+	}
+	if opts.CacheTTL > 0 {
+		repo = newCachedRepository(repo, opts.CacheTTL, opts.CacheSize)
+	}
+
 	return &Plugin{
-		repo: repo,
+		repo:         repo,
+		opts:         opts,
+		series:       newSeriesStore(),
+		fieldConfigs: newFieldConfigStore(),
+		querySlots:   make(chan struct{}, opts.ConcurrentQueries),
 	}
 }
 
+// QuerySlots returns a semaphore limiting the number of queries executed at once by the datasource
+// instance. A query takes a slot by sending to the channel and releases it by receiving from it.
+func (p *Plugin) QuerySlots() chan struct{} {
+	return p.querySlots
+}
+
 // ExecQuery executes metric query based on provided query type.
 func (p *Plugin) ExecQuery(ctx context.Context, q *Query) (data.Frames, error) {
 	var (
@@ -43,10 +112,18 @@ func (p *Plugin) ExecQuery(ctx context.Context, q *Query) (data.Frames, error) {
 	)
 
 	backend.Logger.Debug("ExecQuery", "query", q)
-	switch q.RawQuery {
-	case true:
+	switch {
+	case q.IsAnnotationQuery:
+		dataFrames, err = p.execAnnotationQuery(ctx, q)
+	case q.IsTraceQuery:
+		dataFrames, err = p.execTraceQuery(ctx, q)
+	case q.Format == formatLogs:
+		dataFrames, err = p.execLogsQuery(ctx, q)
+	case q.Format == formatTable:
+		dataFrames, err = p.execTableQuery(ctx, q)
+	case q.RawQuery:
 		dataFrames, err = p.execRawMetricQuery(ctx, q)
-	case false:
+	default:
 		dataFrames, err = p.execStrictMetricQuery(ctx, q)
 	}
 
@@ -54,27 +131,176 @@ func (p *Plugin) ExecQuery(ctx context.Context, q *Query) (data.Frames, error) {
 		return nil, fmt.Errorf("query processing: %w", err)
 	}
 
+	// table of the raw query is unknown
+	if p.opts.CommentFieldConfigs && !q.RawQuery && !q.IsAnnotationQuery && !q.IsTraceQuery {
+		setFieldConfigs(dataFrames, p.tableFieldConfigs(ctx, q.Keyspace, q.Table))
+	}
+
 	return dataFrames, nil
 }
 
 // execRawMetricQuery executes repository ExecRawQuery method and transforms response to data.Frames.
 func (p *Plugin) execRawMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
-	rows, err := p.repo.Select(ctx, q.Target)
+	result, err := p.repo.Select(ctx, q.selectOptions(), q.Target, q.Values...)
 	if err != nil {
 		return nil, fmt.Errorf("repo.Select: %w", err)
 	}
 
-	return makeDataFrames(q, rows), nil
+	return makeDataFrames(q, result)
 }
 
 // execStrictMetricQuery executes repository ExecStrictQuery method and transforms reposonse to data.Frames.
 func (p *Plugin) execStrictMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
-	rows, err := p.repo.Select(ctx, q.BuildStatement(), splitIDs(q.ValueID), q.TimeFrom, q.TimeTo)
+	if q.Reduce != "" {
+		return p.execReducedMetricQuery(ctx, q)
+	}
+
+	statement := q.BuildStatement()
+	if q.Aggregation != "" {
+		if q.Instant || !isNativeAggregation(q.Aggregation) || !p.supportsGroupByTime(ctx) {
+			return p.execAggregatedMetricQuery(ctx, q)
+		}
+		statement = q.BuildAggregateStatement()
+	} else if q.Incremental && !q.Instant && p.series != nil {
+		return p.execIncrementalMetricQuery(ctx, q)
+	}
+
+	result, err := p.selectStrictRows(ctx, q, statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeDataFrames(q, result)
+}
+
+// execAggregatedMetricQuery executes strict query and downsamples rows while
+// they are streamed from repository.
+func (p *Plugin) execAggregatedMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	agg, err := newAggregator(q)
+	if err != nil {
+		return nil, fmt.Errorf("newAggregator: %w", err)
+	}
+
+	values, err := p.strictValues(q)
+	if err != nil {
+		return nil, err
+	}
+
+	notices, err := p.selectStrictFunc(ctx, q, agg.add, q.BuildStatement(), values)
+	if err != nil {
+		return nil, fmt.Errorf("selectStrictFunc: %w", err)
+	}
+
+	result := agg.result()
+	result.Notices = notices
+
+	return makeDataFrames(q, result)
+}
+
+// selectStrictRows executes strict query statement and returns rows sorted by time.
+func (p *Plugin) selectStrictRows(ctx context.Context, q *Query, statement string) (*cassandra.Result, error) {
+	values, err := p.strictValues(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.selectStrict(ctx, q, statement, values)
+	if err != nil {
+		return nil, fmt.Errorf("selectStrict: %w", err)
+	}
+	if q.BucketColumn != "" || q.Parallel {
+		sortRowsByTime(result, q.ColumnTime)
+	}
+
+	return result, nil
+}
+
+// strictValues returns values bound to the strict query statement.
+func (p *Plugin) strictValues(q *Query) ([]interface{}, error) {
+	values := []interface{}{splitIDs(q.ValueID)}
+	if q.BucketColumn != "" {
+		buckets, err := TimeBuckets(q.TimeFrom, q.TimeTo, q.BucketSize, q.BucketFormat)
+		if err != nil {
+			return nil, fmt.Errorf("TimeBuckets: %w", err)
+		}
+		values = append(values, buckets)
+	}
+	values = append(values, cassandra.Timestamp(q.TimeFrom), cassandra.Timestamp(q.TimeTo))
+	if len(q.Filters) > 0 {
+		columns, err := p.repo.GetColumnTypes(q.Keyspace, q.Table)
+		if err != nil {
+			return nil, fmt.Errorf("repo.GetColumnTypes: %w", err)
+		}
+
+		filterValues, err := makeFilterValues(q.Filters, columns)
+		if err != nil {
+			return nil, fmt.Errorf("makeFilterValues: %w", err)
+		}
+		values = append(values, filterValues...)
+	}
+
+	return values, nil
+}
+
+// supportsGroupByTime reports whether the cluster is able to group rows by
+// time buckets with the floor function, which is available since Cassandra 4.1.
+func (p *Plugin) supportsGroupByTime(ctx context.Context) bool {
+	major, minor, ok := parseVersion(p.clusterVersion(ctx))
+	if !ok {
+		return false
+	}
+
+	return major > 4 || major == 4 && minor >= 1
+}
+
+// clusterVersion returns the cluster release version or an empty string if it's unknown.
+// Concurrent queries share a single lookup, see sharedCall. A failure is cached for
+// versionRetryInterval.
+func (p *Plugin) clusterVersion(ctx context.Context) string {
+	p.versionMu.Lock()
+	version, retry := p.version, p.versionRetry
+	p.versionMu.Unlock()
+	if version != "" || time.Now().Before(retry) {
+		return version
+	}
+
+	v, err := sharedCall(ctx, &p.versionGroup, "version", versionTimeout, func(ctx context.Context) (interface{}, error) {
+		version, err := p.repo.GetVersion(ctx)
+
+		p.versionMu.Lock()
+		defer p.versionMu.Unlock()
+		if err != nil {
+			backend.Logger.Warn("Failed to get cluster version, aggregating on the datasource side", "error", err)
+			p.versionRetry = time.Now().Add(versionRetryInterval)
+			return "", nil
+		}
+		p.version = version
+
+		return version, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("repo.ExecStrictQuery: %w", err)
+		return ""
 	}
 
-	return makeDataFrames(q, rows), nil
+	return v.(string)
+}
+
+// sharedCall calls fn once for the concurrent callers of the same key. The call is
+// shared, so it's not cancelled when the caller which started it is gone, it's limited
+// by the timeout instead. Every caller stops waiting when its own context is done.
+func sharedCall(ctx context.Context, group *singleflight.Group, key string, timeout time.Duration, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	resultCh := group.DoChan(key, func() (interface{}, error) {
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		return fn(callCtx)
+	})
+	select {
+	case res := <-resultCh:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 // GetKeyspaces fetches and returns Cassandra's list of keyspaces.
@@ -112,14 +338,14 @@ func (p *Plugin) GetColumns(keyspace, table, needType string) ([]string, error)
 func (p *Plugin) GetVariables(ctx context.Context, query string) ([]Variable, error) {
 	backend.Logger.Debug("GetVariables", "query", query)
 
-	idRows, err := p.repo.Select(ctx, query)
+	result, err := p.repo.Select(ctx, cassandra.SelectOptions{}, query)
 	if err != nil {
 		return nil, fmt.Errorf("repo.Select: %w", err)
 	}
 
-	vars := make([]Variable, 0, len(idRows))
-	for _, rows := range idRows {
-		for _, row := range rows {
+	vars := make([]Variable, 0, len(result.Rows))
+	for _, id := range result.OrderedIDs() {
+		for _, row := range result.Rows[id] {
 			vars = append(vars, makeVariableFromRow(row))
 		}
 	}
@@ -142,6 +368,30 @@ func (p *Plugin) Dispose() {
 	p.repo.Close()
 }
 
+// parseVersion returns major and minor numbers of a version like "4.1.3" or "5.0-beta1".
+func parseVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	minorDigits := parts[1]
+	if i := strings.IndexFunc(minorDigits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minorDigits = minorDigits[:i]
+	}
+	minor, err = strconv.Atoi(minorDigits)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
 func splitIDs(s string) []string {
 	var ids []string
 	for _, id := range strings.Split(s, ",") {
@@ -151,22 +401,55 @@ func splitIDs(s string) []string {
 	return ids
 }
 
-func makeDataFrames(q *Query, rows map[string][]cassandra.Row) data.Frames {
+func makeDataFrames(q *Query, result *cassandra.Result) (data.Frames, error) {
+	if q.Format == formatWide {
+		return makeWideFrames(q, result)
+	}
+
 	var frames data.Frames
-	for id, points := range rows {
-		frame := makeDataFrameFromRows(id, q.AliasID, points)
-		if q.IsAlertQuery {
-			// alerting doesn't support narrow frames
-			frame = narrowFrameToWideFrame(frame)
+	for _, id := range result.OrderedIDs() {
+		points := result.Rows[id]
+		groups := []labeledRows{{rows: points}}
+		if q.ExpandCollections {
+			groups = expandCollections(points)
+		}
+
+		for _, group := range groups {
+			frame := makeDataFrameFromRows(id, q.AliasID, q.columnAliases(), group.rows)
+			setLabels(frame, group.labels)
+			if q.IsAlertQuery {
+				// alerting doesn't support narrow frames
+				frames = append(frames, narrowFrameToWideFrames(frame)...)
+				continue
+			}
+			frames = append(frames, frame)
 		}
-		frames = append(frames, frame)
+	}
+	sortFrames(frames, q.SortBy)
+
+	return appendNotices(frames, result.Notices), nil
+}
+
+// appendNotices attaches warning notices to the first frame only to be shown once.
+// An empty frame carrying the notices is added when there are no frames,
+// e.g. when the result is truncated before the first series is complete.
+func appendNotices(frames data.Frames, notices []string) data.Frames {
+	if len(notices) == 0 {
+		return frames
+	}
+	if len(frames) == 0 || frames[0] == nil {
+		frames = append(data.Frames{data.NewFrame("")}, frames...)
+	}
+	for _, notice := range notices {
+		frames[0].AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: notice})
 	}
 
 	return frames
 }
 
 // makeDataFrameFromRows creates data frames from time series points returned by repository.
-func makeDataFrameFromRows(id string, alias string, rows []cassandra.Row) *data.Frame {
+// Alias is applied to all numeric fields, unless the field has its own alias in columnAliases.
+func makeDataFrameFromRows(id string, alias string, columnAliases map[string]string, rows []cassandra.Row) *data.Frame {
 	if len(rows) == 0 {
 		return nil
 	}
@@ -177,24 +460,71 @@ func makeDataFrameFromRows(id string, alias string, rows []cassandra.Row) *data.
 	alias = formatAlias(alias, rows[0].Fields)
 	fields := make([]*data.Field, 0, len(rows[0].Columns))
 	for _, colName := range rows[0].Columns {
-		field := data.NewFieldFromFieldType(data.FieldTypeFor(rows[0].Fields[colName]), 0)
+		field := data.NewFieldFromFieldType(columnFieldType(colName, rows), len(rows))
 		field.Name = colName
-		if alias != "" && field.Type().Numeric() {
-			field.SetConfig(&data.FieldConfig{DisplayNameFromDS: alias})
+		for i, r := range rows {
+			if val := r.Fields[colName]; val != nil {
+				field.SetConcrete(i, val)
+			}
+		}
+		if unit := rows[0].Units[colName]; unit != "" {
+			field.SetConfig(&data.FieldConfig{Unit: unit})
+		}
+		fieldAlias := alias
+		if columnAlias, ok := columnAliases[colName]; ok {
+			fieldAlias = formatAlias(columnAlias, rows[0].Fields)
+		}
+		if fieldAlias != "" && field.Type().Numeric() {
+			if field.Config == nil {
+				field.SetConfig(&data.FieldConfig{})
+			}
+			field.Config.DisplayNameFromDS = fieldAlias
 		}
 		fields = append(fields, field)
 	}
 	frame.Fields = fields
 
+	return frame
+}
+
+// firstTimeColumn returns the first column of the rows which is a timestamp
+// by the reported type or the value of the first row.
+func firstTimeColumn(rows []cassandra.Row) (string, error) {
+	for _, colName := range rows[0].Columns {
+		if _, ok := rows[0].Fields[colName].(time.Time); ok || rows[0].Types[colName].Time() {
+			return colName, nil
+		}
+	}
+
+	return "", errors.New("timestamp column is required")
+}
+
+// columnFieldType returns a field type for the column values. The type reported
+// by repository is preferred, otherwise it is guessed from the first non-NULL
+// value. Nullable type is returned when the column contains NULL values.
+func columnFieldType(colName string, rows []cassandra.Row) data.FieldType {
+	fieldType := rows[0].Types[colName]
+	nullable := false
 	for _, r := range rows {
-		values := make([]interface{}, 0, len(r.Fields))
-		for _, colName := range r.Columns {
-			values = append(values, r.Fields[colName])
+		val := r.Fields[colName]
+		if val == nil {
+			nullable = true
+			continue
+		}
+		if fieldType == data.FieldTypeUnknown {
+			fieldType = data.FieldTypeFor(val)
 		}
-		frame.AppendRow(values...)
 	}
 
-	return frame
+	// column of unknown type contains NULLs only
+	if fieldType == data.FieldTypeUnknown {
+		fieldType = data.FieldTypeString
+	}
+	if nullable {
+		return fieldType.NullableType()
+	}
+
+	return fieldType
 }
 
 // formatAlias performs legend alias interpolation.
@@ -225,42 +555,111 @@ func formatAlias(alias string, values map[string]interface{}) string {
 	return string(formattedAlias)
 }
 
-// narrowFrameToWideFrame performs rudimentary frames conversion from narrow to wide format.
-// It puts non-TS fields to labels and removes from fields list. Conflicting labels are replaced.
-// Any other field is ignored and could cause grafana alerting error during alert query execution.
+// narrowFrameToWideFrames performs frames conversion from narrow to wide format.
+// Rows are split to a separate frame for every unique combination of non-TS field values,
+// which are put to labels of numeric fields and removed from fields list. Conflicting labels are replaced.
+// Rows without time are dropped and a warning is attached to the first frame.
 // https://grafana.com/developers/plugin-tools/introduction/data-frames#data-frames-as-time-series
-func narrowFrameToWideFrame(frame *data.Frame) *data.Frame {
-	if len(frame.Fields) == 0 {
-		return frame
+func narrowFrameToWideFrames(frame *data.Frame) data.Frames {
+	if frame == nil || len(frame.Fields) == 0 {
+		return data.Frames{frame}
 	}
 
-	labels := makeLabelsFromNonTSFields(frame)
-	for i := 0; i < len(frame.Fields); i++ {
-		if frame.Fields[i].Type().Numeric() {
-			if frame.Fields[i].Labels == nil {
-				frame.Fields[i].Labels = make(map[string]string, len(labels))
+	timeIndices := frame.TypeIndices(data.FieldTypeTime, data.FieldTypeNullableTime)
+
+	var (
+		groups  [][]int
+		labels  []map[string]string
+		index   = make(map[string]int)
+		dropped int
+	)
+	for i := 0; i < frame.Rows(); i++ {
+		if len(timeIndices) > 0 {
+			if _, ok := frame.ConcreteAt(timeIndices[0], i); !ok {
+				dropped++
+				continue
 			}
-			for k, v := range labels {
-				frame.Fields[i].Labels[k] = v
+		}
+
+		rowLabels := makeLabelsFromNonTSFields(frame, i)
+		key := data.Labels(rowLabels).String()
+		j, ok := index[key]
+		if !ok {
+			j = len(groups)
+			index[key] = j
+			groups = append(groups, nil)
+			labels = append(labels, rowLabels)
+		}
+		groups[j] = append(groups[j], i)
+	}
+
+	var frames data.Frames
+	switch {
+	case len(groups) == 0:
+		frames = data.Frames{emptyFrameCopy(frame)}
+	case len(groups) == 1 && dropped == 0:
+		// the common case of a single series doesn't need a copy
+		setLabels(frame, labels[0])
+		frames = data.Frames{frame}
+	default:
+		for j, rows := range groups {
+			split := emptyFrameCopy(frame)
+			for _, i := range rows {
+				split.AppendRow(frame.RowCopy(i)...)
 			}
+			setLabels(split, labels[j])
+			frames = append(frames, split)
+		}
+	}
+
+	for _, f := range frames {
+		removeNonTSFields(f)
+	}
+	if dropped > 0 {
+		frames[0].AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d rows of series %s without time have been dropped", dropped, frame.Name),
+		})
+	}
+
+	return frames
+}
+
+// emptyFrameCopy returns a copy of the frame without rows. Unlike data.Frame.EmptyCopy,
+// field configs are kept and fields without labels don't get empty ones.
+func emptyFrameCopy(frame *data.Frame) *data.Frame {
+	frameCopy := &data.Frame{Name: frame.Name, RefID: frame.RefID, Fields: make([]*data.Field, 0, len(frame.Fields))}
+	if frame.Meta != nil {
+		meta := *frame.Meta
+		frameCopy.Meta = &meta
+	}
+	for _, field := range frame.Fields {
+		fieldCopy := data.NewFieldFromFieldType(field.Type(), 0)
+		fieldCopy.Name = field.Name
+		fieldCopy.Config = field.Config
+		if field.Labels != nil {
+			fieldCopy.Labels = field.Labels.Copy()
 		}
+		frameCopy.Fields = append(frameCopy.Fields, fieldCopy)
 	}
 
-	return removeNonTSFields(frame)
+	return frameCopy
 }
 
 // makeLabelsFromNonTSFields creates map of labels and their corresponding
-// values from all fields that are not numeric or timestamps. Only first
-// value from each field used as label value.
-func makeLabelsFromNonTSFields(frame *data.Frame) map[string]string {
+// values of the row from all fields that are not numeric or timestamps.
+// NULL values are skipped.
+func makeLabelsFromNonTSFields(frame *data.Frame, row int) map[string]string {
 	labels := make(map[string]string)
-	if frame == nil || len(frame.Fields) == 0 || frame.Fields[0].Len() == 0 {
+	if frame == nil || len(frame.Fields) == 0 || row >= frame.Fields[0].Len() {
 		return labels
 	}
 
 	for _, f := range frame.Fields {
 		if !f.Type().Numeric() && !f.Type().Time() {
-			labels[f.Name] = fmt.Sprintf("%v", f.CopyAt(0))
+			if val, ok := f.ConcreteAt(row); ok {
+				labels[f.Name] = fmt.Sprintf("%v", val)
+			}
 		}
 	}
 
@@ -269,26 +668,19 @@ func makeLabelsFromNonTSFields(frame *data.Frame) map[string]string {
 
 // removeNonTSFields deletes all fields that are not numeric or timestamps
 // from frame. These values should be previously stored to labels
-// using makeLabelsFromNonTSFields method. Result is not stable, e.g.
-// elements can change their positions during filtration.
+// using makeLabelsFromNonTSFields method. Order of the kept fields is preserved.
 func removeNonTSFields(frame *data.Frame) *data.Frame {
 	if frame == nil {
 		return nil
 	}
 
-	i, j := 0, len(frame.Fields)-1
-	for i <= j {
-		// keep numeric and timestamp fields
-		if frame.Fields[i].Type().Numeric() || frame.Fields[i].Type().Time() {
-			i++
-			continue
+	fields := frame.Fields[:0]
+	for _, field := range frame.Fields {
+		if field.Type().Numeric() || field.Type().Time() {
+			fields = append(fields, field)
 		}
-		// move all the other fields to the end of the Fields slice
-		frame.Fields[i], frame.Fields[j] = frame.Fields[j], frame.Fields[i]
-		j--
 	}
-
-	frame.Fields = frame.Fields[0:i]
+	frame.Fields = fields
 
 	return frame
 }
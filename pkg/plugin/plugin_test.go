@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"sort"
 	"testing"
 	"time"
@@ -12,14 +13,28 @@ import (
 )
 
 type repositoryMock struct {
-	onSelect       func(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error)
-	onGetKeyspaces func(ctx context.Context) ([]string, error)
-	onGetTables    func(keyspace string) ([]string, error)
-	onGetColumns   func(keyspace, table, needType string) ([]string, error)
+	onSelect          func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error)
+	onSelectFunc      func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error)
+	onSelectPageFunc  func(ctx context.Context, opts cassandra.SelectOptions, pageState []byte, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, []byte, error)
+	onGetKeyspaces    func(ctx context.Context) ([]string, error)
+	onGetTables       func(keyspace string) ([]string, error)
+	onGetColumns      func(keyspace, table, needType string) ([]string, error)
+	onGetVersion      func(ctx context.Context) (string, error)
+	onGetColumnTypes  func(keyspace, table string) (map[string]cassandra.ColumnType, error)
+	onGetTraceSpans   func(ctx context.Context, keyspace, table string, traceID []byte) ([]cassandra.Span, error)
+	onGetTableComment func(ctx context.Context, keyspace, table string) (string, error)
 }
 
-func (m *repositoryMock) Select(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error) {
-	return m.onSelect(ctx, query, values...)
+func (m *repositoryMock) Select(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+	return m.onSelect(ctx, opts, query, values...)
+}
+
+func (m *repositoryMock) SelectFunc(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+	return m.onSelectFunc(ctx, opts, fn, query, values...)
+}
+
+func (m *repositoryMock) SelectPageFunc(ctx context.Context, opts cassandra.SelectOptions, pageState []byte, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, []byte, error) {
+	return m.onSelectPageFunc(ctx, opts, pageState, fn, query, values...)
 }
 
 func (m *repositoryMock) GetKeyspaces(ctx context.Context) ([]string, error) {
@@ -34,6 +49,22 @@ func (m *repositoryMock) GetColumns(keyspace, table, needType string) ([]string,
 	return m.onGetColumns(keyspace, table, needType)
 }
 
+func (m *repositoryMock) GetColumnTypes(keyspace, table string) (map[string]cassandra.ColumnType, error) {
+	return m.onGetColumnTypes(keyspace, table)
+}
+
+func (m *repositoryMock) GetTraceSpans(ctx context.Context, keyspace, table string, traceID []byte) ([]cassandra.Span, error) {
+	return m.onGetTraceSpans(ctx, keyspace, table, traceID)
+}
+
+func (m *repositoryMock) GetTableComment(ctx context.Context, keyspace, table string) (string, error) {
+	return m.onGetTableComment(ctx, keyspace, table)
+}
+
+func (m *repositoryMock) GetVersion(ctx context.Context) (string, error) {
+	return m.onGetVersion(ctx)
+}
+
 func (m *repositoryMock) Ping(_ context.Context) error { return nil }
 
 func (m *repositoryMock) Close() {}
@@ -48,8 +79,8 @@ func TestPlugin_ExecQuery(t *testing.T) {
 		{
 			name: "Raw Query",
 			repo: &repositoryMock{
-				onSelect: func(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error) {
-					return map[string][]cassandra.Row{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					return &cassandra.Result{Rows: map[string][]cassandra.Row{
 						"1": {
 							{
 								Columns: []string{"ID", "Value", "Time"},
@@ -86,7 +117,7 @@ func TestPlugin_ExecQuery(t *testing.T) {
 								Fields:  map[string]interface{}{"ID": "2", "Value": 1.619, "Time": time.UnixMilli(1257894003000).UTC()},
 							},
 						},
-					}, nil
+					}}, nil
 				},
 			},
 			query: &Query{
@@ -125,8 +156,8 @@ func TestPlugin_ExecQuery(t *testing.T) {
 		{
 			name: "Strict Query",
 			repo: &repositoryMock{
-				onSelect: func(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error) {
-					return map[string][]cassandra.Row{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					return &cassandra.Result{Rows: map[string][]cassandra.Row{
 						"1": {
 							{
 								Columns: []string{"ID", "Value", "Time"},
@@ -145,7 +176,7 @@ func TestPlugin_ExecQuery(t *testing.T) {
 								Fields:  map[string]interface{}{"ID": "1", "Value": 1.618, "Time": time.UnixMilli(1257894003000).UTC()},
 							},
 						},
-					}, nil
+					}}, nil
 				},
 			},
 			query: &Query{
@@ -193,6 +224,66 @@ func TestPlugin_ExecQuery(t *testing.T) {
 	}
 }
 
+func TestPlugin_ExecQuery_limits(t *testing.T) {
+	var gotOpts cassandra.SelectOptions
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			gotOpts = opts
+			return &cassandra.Result{
+				Rows: map[string][]cassandra.Row{
+					"1": {
+						{
+							Columns: []string{"ID", "Value"},
+							Fields:  map[string]interface{}{"ID": "1", "Value": 3.141},
+						},
+					},
+				},
+				Notices: []string{"Result is truncated: limit of 1 rows reached"},
+			}, nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	dataFrames, err := p.ExecQuery(context.TODO(), &Query{RawQuery: true, Target: "SELECT ID, Value FROM Keyspace.Table", PageSize: 100, MaxRows: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, cassandra.SelectOptions{PageSize: 100, MaxRows: 1}, gotOpts)
+	assert.Len(t, dataFrames, 1)
+	assert.Equal(t, []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 1 rows reached"}}, dataFrames[0].Meta.Notices)
+}
+
+func Test_appendNotices(t *testing.T) {
+	notice := data.Notice{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 1 rows reached"}
+
+	testCases := []struct {
+		name    string
+		frames  data.Frames
+		notices []string
+		want    data.Frames
+	}{
+		{
+			name: "no notices",
+		},
+		{
+			name:    "no frames",
+			notices: []string{notice.Text},
+			want:    data.Frames{data.NewFrame("").SetMeta(&data.FrameMeta{Notices: []data.Notice{notice}})},
+		},
+		{
+			name:    "first frame",
+			frames:  data.Frames{data.NewFrame("1"), data.NewFrame("2")},
+			notices: []string{notice.Text},
+			want:    data.Frames{data.NewFrame("1").SetMeta(&data.FrameMeta{Notices: []data.Notice{notice}}), data.NewFrame("2")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, appendNotices(tc.frames, tc.notices))
+		})
+	}
+}
+
 func TestPlugin_GetVariables(t *testing.T) {
 	testCases := []struct {
 		name string
@@ -202,8 +293,8 @@ func TestPlugin_GetVariables(t *testing.T) {
 		{
 			name: "response with labels",
 			repo: &repositoryMock{
-				onSelect: func(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error) {
-					return map[string][]cassandra.Row{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					return &cassandra.Result{Rows: map[string][]cassandra.Row{
 						"1": {
 							{
 								Columns: []string{"Value", "Label"},
@@ -216,19 +307,19 @@ func TestPlugin_GetVariables(t *testing.T) {
 								Fields:  map[string]interface{}{"Value": "2", "Label": "Text2"},
 							},
 						},
-					}, nil
+					}, IDs: []string{"2", "1"}}, nil
 				},
 			},
 			want: []Variable{
-				{Value: "1", Label: "Text1"},
 				{Value: "2", Label: "Text2"},
+				{Value: "1", Label: "Text1"},
 			},
 		},
 		{
 			name: "response without labels",
 			repo: &repositoryMock{
-				onSelect: func(ctx context.Context, query string, values ...interface{}) (rows map[string][]cassandra.Row, err error) {
-					return map[string][]cassandra.Row{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					return &cassandra.Result{Rows: map[string][]cassandra.Row{
 						"1": {
 							{
 								Columns: []string{"Value"},
@@ -241,7 +332,7 @@ func TestPlugin_GetVariables(t *testing.T) {
 								Fields:  map[string]interface{}{"Value": "2"},
 							},
 						},
-					}, nil
+					}}, nil
 				},
 			},
 			want: []Variable{
@@ -256,10 +347,6 @@ func TestPlugin_GetVariables(t *testing.T) {
 			p := &Plugin{repo: tc.repo}
 			vars, err := p.GetVariables(context.TODO(), "SELECT * FROM keyspace.table")
 			assert.NoError(t, err)
-
-			sort.Slice(vars, func(i, j int) bool {
-				return vars[i].Value < vars[j].Value
-			})
 			assert.Equal(t, tc.want, vars)
 		})
 	}
@@ -267,11 +354,12 @@ func TestPlugin_GetVariables(t *testing.T) {
 
 func Test_makeDataFrameFromRows(t *testing.T) {
 	testCases := []struct {
-		name  string
-		id    string
-		alias string
-		rows  []cassandra.Row
-		want  *data.Frame
+		name          string
+		id            string
+		alias         string
+		columnAliases map[string]string
+		rows          []cassandra.Row
+		want          *data.Frame
 	}{
 		{
 			name:  "nil points",
@@ -436,6 +524,27 @@ func Test_makeDataFrameFromRows(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "multiple value columns with column aliases",
+			id:            "test",
+			alias:         "{{ ID }} temperature",
+			columnAliases: map[string]string{"Humidity": "{{ ID }} humidity"},
+			rows: []cassandra.Row{
+				{
+					Columns: []string{"ID", "Temperature", "Humidity", "Time"},
+					Fields:  map[string]interface{}{"ID": "test", "Temperature": 21.5, "Humidity": 40.0, "Time": time.UnixMilli(1257894000000).UTC()},
+				},
+			},
+			want: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test"}),
+					data.NewField("Temperature", nil, []float64{21.5}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "test temperature"}),
+					data.NewField("Humidity", nil, []float64{40.0}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "test humidity"}),
+					data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894000000).UTC()}),
+				},
+			},
+		},
 		{
 			name:  "multi points with template alias",
 			id:    "test",
@@ -472,26 +581,98 @@ func Test_makeDataFrameFromRows(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "multi points with nulls",
+			id:    "test",
+			alias: "",
+			rows: []cassandra.Row{
+				{
+					Columns: []string{"ID", "Value", "Time"},
+					Fields:  map[string]interface{}{"ID": "test", "Value": nil, "Time": time.UnixMilli(1257894000000).UTC()},
+					Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Value": data.FieldTypeFloat64, "Time": data.FieldTypeTime},
+				},
+				{
+					Columns: []string{"ID", "Value", "Time"},
+					Fields:  map[string]interface{}{"ID": "test", "Value": 6.283, "Time": time.UnixMilli(1257894001000).UTC()},
+					Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Value": data.FieldTypeFloat64, "Time": data.FieldTypeTime},
+				},
+			},
+			want: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test", "test"}),
+					data.NewField("Value", nil, []*float64{nil, pointer(6.283)}),
+					data.NewField("Time", nil, []time.Time{
+						time.UnixMilli(1257894000000).UTC(),
+						time.UnixMilli(1257894001000).UTC(),
+					}),
+				},
+			},
+		},
+		{
+			name:  "point with unit and alias",
+			id:    "test",
+			alias: "alias",
+			rows: []cassandra.Row{
+				{
+					Columns: []string{"ID", "Period", "Time"},
+					Fields:  map[string]interface{}{"ID": "test", "Period": int64(1000), "Time": time.UnixMilli(1257894000000).UTC()},
+					Types:   map[string]data.FieldType{"ID": data.FieldTypeString, "Period": data.FieldTypeInt64, "Time": data.FieldTypeTime},
+					Units:   map[string]string{"Period": "ns"},
+				},
+			},
+			want: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test"}),
+					data.NewField("Period", nil, []int64{1000}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias", Unit: "ns"}),
+					data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894000000).UTC()}),
+				},
+			},
+		},
+		{
+			name:  "nulls without types",
+			id:    "test",
+			alias: "",
+			rows: []cassandra.Row{
+				{
+					Columns: []string{"ID", "Value", "Comment"},
+					Fields:  map[string]interface{}{"ID": "test", "Value": nil, "Comment": nil},
+				},
+				{
+					Columns: []string{"ID", "Value", "Comment"},
+					Fields:  map[string]interface{}{"ID": "test", "Value": int64(1), "Comment": nil},
+				},
+			},
+			want: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test", "test"}),
+					data.NewField("Value", nil, []*int64{nil, pointer(int64(1))}),
+					data.NewField("Comment", nil, []*string{nil, nil}),
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dataFrame := makeDataFrameFromRows(tc.id, tc.alias, tc.rows)
+			dataFrame := makeDataFrameFromRows(tc.id, tc.alias, tc.columnAliases, tc.rows)
 			assert.EqualValues(t, tc.want, dataFrame)
 		})
 	}
 }
 
-func Test_narrowFrameToWideFrame(t *testing.T) {
+func Test_narrowFrameToWideFrames(t *testing.T) {
 	testCases := []struct {
 		name  string
 		input *data.Frame
-		want  *data.Frame
+		want  data.Frames
 	}{
 		{
 			name:  "empty",
 			input: &data.Frame{},
-			want:  &data.Frame{},
+			want:  data.Frames{&data.Frame{}},
 		},
 		{
 			name: "multi points without labels",
@@ -508,7 +689,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 					}),
 				},
 			},
-			want: &data.Frame{
+			want: data.Frames{{
 				Name: "test",
 				Fields: []*data.Field{
 					data.NewField("Value", map[string]string{"ID": "test"}, []float64{3.141, 6.283, 2.718, 1.618}),
@@ -519,7 +700,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 						time.UnixMilli(1257894003000).UTC(),
 					}),
 				},
-			},
+			}},
 		},
 		{
 			name: "multiple non-TS fields",
@@ -537,7 +718,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 					data.NewField("Location", nil, []string{"room", "room", "room", "room"}),
 				},
 			},
-			want: &data.Frame{
+			want: data.Frames{{
 				Name: "test",
 				Fields: []*data.Field{
 					data.NewField("Value", map[string]string{"ID": "test", "Location": "room"}, []float64{3.141, 6.283, 2.718, 1.618}),
@@ -548,7 +729,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 						time.UnixMilli(1257894003000).UTC(),
 					}),
 				},
-			},
+			}},
 		},
 		{
 			name: "multi points with labels",
@@ -565,7 +746,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 					}),
 				},
 			},
-			want: &data.Frame{
+			want: data.Frames{{
 				Name: "test",
 				Fields: []*data.Field{
 					data.NewField("Value", map[string]string{"SomeLabel": "SomeValue", "ID": "test"}, []float64{3.141, 6.283, 2.718, 1.618}),
@@ -576,7 +757,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 						time.UnixMilli(1257894003000).UTC(),
 					}),
 				},
-			},
+			}},
 		},
 		{
 			name: "multi points with labels conflict",
@@ -593,7 +774,7 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 					}),
 				},
 			},
-			want: &data.Frame{
+			want: data.Frames{{
 				Name: "test",
 				Fields: []*data.Field{
 					data.NewField("Value", map[string]string{"ID": "test"}, []float64{3.141, 6.283, 2.718, 1.618}),
@@ -604,20 +785,74 @@ func Test_narrowFrameToWideFrame(t *testing.T) {
 						time.UnixMilli(1257894003000).UTC(),
 					}),
 				},
+			}},
+		},
+		{
+			name: "varying non-TS field",
+			input: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test", "test", "test"}),
+					data.NewField("Value", nil, []float64{3.141, 6.283, 2.718}),
+					data.NewField("Location", nil, []*string{pointer("room1"), pointer("room2"), nil}),
+					data.NewField("Time", nil, []time.Time{
+						time.UnixMilli(1257894000000).UTC(),
+						time.UnixMilli(1257894001000).UTC(),
+						time.UnixMilli(1257894002000).UTC(),
+					}),
+				},
+			},
+			want: data.Frames{
+				{
+					Name: "test",
+					Fields: []*data.Field{
+						data.NewField("Value", map[string]string{"ID": "test", "Location": "room1"}, []float64{3.141}),
+						data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894000000).UTC()}),
+					},
+				},
+				{
+					Name: "test",
+					Fields: []*data.Field{
+						data.NewField("Value", map[string]string{"ID": "test", "Location": "room2"}, []float64{6.283}),
+						data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894001000).UTC()}),
+					},
+				},
+				{
+					Name: "test",
+					Fields: []*data.Field{
+						data.NewField("Value", map[string]string{"ID": "test"}, []float64{2.718}),
+						data.NewField("Time", nil, []time.Time{time.UnixMilli(1257894002000).UTC()}),
+					},
+				},
+			},
+		},
+		{
+			name: "rows without time",
+			input: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test", "test"}),
+					data.NewField("Value", nil, []float64{3.141, 6.283}),
+					data.NewField("Time", nil, []*time.Time{nil, pointer(time.UnixMilli(1257894001000).UTC())}),
+				},
 			},
+			want: data.Frames{{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("Value", map[string]string{"ID": "test"}, []float64{6.283}),
+					data.NewField("Time", nil, []*time.Time{pointer(time.UnixMilli(1257894001000).UTC())}),
+				},
+				Meta: &data.FrameMeta{Notices: []data.Notice{{
+					Severity: data.NoticeSeverityWarning,
+					Text:     "1 rows of series test without time have been dropped",
+				}}},
+			}},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			frame := narrowFrameToWideFrame(tc.input)
-			sort.Slice(frame.Fields, func(i, j int) bool {
-				return frame.Fields[i].Name < frame.Fields[j].Name
-			})
-			sort.Slice(tc.want.Fields, func(i, j int) bool {
-				return tc.want.Fields[i].Name < tc.want.Fields[j].Name
-			})
-			assert.Equal(t, tc.want, frame)
+			assert.Equal(t, tc.want, narrowFrameToWideFrames(tc.input))
 		})
 	}
 }
@@ -626,6 +861,7 @@ func Test_makeLabelsFromNonTSFields(t *testing.T) {
 	testCases := []struct {
 		name  string
 		input *data.Frame
+		row   int
 		want  map[string]string
 	}{
 		{
@@ -688,6 +924,30 @@ func Test_makeLabelsFromNonTSFields(t *testing.T) {
 			},
 			want: map[string]string{"ID": "test1"},
 		},
+		{
+			name: "one string field with different values, second row",
+			input: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []string{"test1", "test2"}),
+					data.NewField("Value", nil, []float64{3.141, 6.283}),
+				},
+			},
+			row:  1,
+			want: map[string]string{"ID": "test2"},
+		},
+		{
+			name: "nullable string fields",
+			input: &data.Frame{
+				Name: "test",
+				Fields: []*data.Field{
+					data.NewField("ID", nil, []*string{pointer("test1"), pointer("test2")}),
+					data.NewField("Comment", nil, []*string{nil, pointer("comment")}),
+					data.NewField("Value", nil, []float64{3.141, 6.283}),
+				},
+			},
+			want: map[string]string{"ID": "test1"},
+		},
 		{
 			name: "multiple string fields, simple",
 			input: &data.Frame{
@@ -731,7 +991,7 @@ func Test_makeLabelsFromNonTSFields(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			labels := makeLabelsFromNonTSFields(tc.input)
+			labels := makeLabelsFromNonTSFields(tc.input, tc.row)
 			assert.Equal(t, tc.want, labels)
 		})
 	}
@@ -873,20 +1133,7 @@ func Test_removeNonTSFields(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			frame := removeNonTSFields(tc.input)
-			if frame != nil {
-				sort.Slice(frame.Fields, func(i, j int) bool {
-					return frame.Fields[i].Name < frame.Fields[j].Name
-				})
-			}
-
-			if tc.want != nil {
-				sort.Slice(tc.want.Fields, func(i, j int) bool {
-					return tc.want.Fields[i].Name < tc.want.Fields[j].Name
-				})
-			}
-
-			assert.Equal(t, tc.want, frame)
+			assert.Equal(t, tc.want, removeNonTSFields(tc.input))
 		})
 	}
 }
@@ -985,3 +1232,59 @@ func Test_formatAlias(t *testing.T) {
 		})
 	}
 }
+
+func pointer[T any](v T) *T {
+	return &v
+}
+
+func TestPlugin_supportsGroupByTime(t *testing.T) {
+	var calls int
+	version, versionErr := "", errors.New("connection refused")
+	repo := &repositoryMock{
+		onGetVersion: func(ctx context.Context) (string, error) {
+			calls++
+			assert.NoError(t, ctx.Err())
+			return version, versionErr
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the lookup is not cancelled with the query, the failure is cached
+	assert.False(t, p.supportsGroupByTime(cancelledCtx))
+	assert.False(t, p.supportsGroupByTime(context.TODO()))
+	assert.Equal(t, 1, calls)
+
+	p.versionRetry = time.Now()
+	version, versionErr = "4.1.3", nil
+	assert.True(t, p.supportsGroupByTime(context.TODO()))
+	assert.True(t, p.supportsGroupByTime(context.TODO()))
+	assert.Equal(t, 2, calls)
+}
+
+func Test_parseVersion(t *testing.T) {
+	testCases := []struct {
+		version string
+		major   int
+		minor   int
+		ok      bool
+	}{
+		{version: "3.11.4", major: 3, minor: 11, ok: true},
+		{version: "4.1.3", major: 4, minor: 1, ok: true},
+		{version: "5.0-beta1", major: 5, minor: 0, ok: true},
+		{version: "6.8.0.1", major: 6, minor: 8, ok: true},
+		{version: "unknown", ok: false},
+		{version: "", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			major, minor, ok := parseVersion(tc.version)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.major, major)
+			assert.Equal(t, tc.minor, minor)
+		})
+	}
+}
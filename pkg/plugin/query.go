@@ -2,26 +2,75 @@ package plugin
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
+// ValueColumn is an additional value column of a strict query.
+type ValueColumn struct {
+	Column string
+	// Alias is a legend alias template of the column,
+	// Query.AliasID is used when it's empty.
+	Alias string
+}
+
 type Query struct {
-	RawQuery       bool
-	Target         string
-	Keyspace       string
-	Table          string
-	ColumnValue    string
-	ColumnID       string
-	ValueID        string
-	AliasID        string
-	ColumnTime     string
-	TimeFrom       time.Time
-	TimeTo         time.Time
-	AllowFiltering bool
-	Instant        bool
-	IsAlertQuery   bool
+	RawQuery bool
+	Target   string
+	// Values are bound to the raw query markers.
+	Values            []interface{}
+	Keyspace          string
+	Table             string
+	ColumnValue       string
+	ValueColumns      []ValueColumn
+	ColumnID          string
+	ValueID           string
+	AliasID           string
+	ColumnTime        string
+	TimeFrom          time.Time
+	TimeTo            time.Time
+	AllowFiltering    bool
+	Instant           bool
+	IsAlertQuery      bool
+	IsAnnotationQuery bool
+	IsTraceQuery      bool
+	ExpandCollections bool
+	PageSize          int
+	MaxRows           int
+	MaxBytes          int
+	Aggregation       string
+	Interval          time.Duration
+	MaxDataPoints     int64
+	Filters           []Filter
+	// BucketColumn is a partition key column, which stores time buckets of
+	// BucketSize in BucketFormat. Buckets covering the time range are queried.
+	BucketColumn string
+	BucketSize   string
+	BucketFormat string
+	// Parallel makes a separate query for every ID instead of a single IN query.
+	Parallel bool
+	// NoCache requests fresh data even if the result cache is enabled.
+	NoCache bool
+	// Incremental reuses rows of the previous query execution and fetches only the new ones.
+	Incremental bool
+	// Format is the result format, either time series (default), wide time series, table or logs.
+	Format string
+	// LogBodyColumn and LogLevelColumn are the columns of the log line body and level of logs format.
+	LogBodyColumn  string
+	LogLevelColumn string
+	// PageState is a base64 encoded paging state of the logs page to fetch, the first page when empty.
+	PageState string
+	// TraceID is a hex ID of the trace to read with trace query.
+	TraceID string
+	// SortBy is the order of time series: id, alias or last value.
+	// Series are returned in the order of the query result when empty.
+	SortBy string
+	// Reduce is the function reducing every series to a single value over the time range,
+	// one of the aggregation functions. Reduced query returns numeric frames instead of time series.
+	Reduce string
 }
 
 // BuildStatement builds cassandra query statement with positional parameters.
@@ -37,15 +86,17 @@ func (q *Query) BuildStatement() string {
 	}
 
 	statement := fmt.Sprintf(
-		"SELECT %s, %s, %s FROM %s.%s WHERE %s IN ? AND %s >= ? AND %s <= ?%s%s",
+		"SELECT %s, %s, %s FROM %s.%s WHERE %s%s AND %s >= ? AND %s <= ?%s%s%s",
 		q.ColumnID,
-		q.ColumnValue,
+		strings.Join(q.valueColumns(), ", "),
 		q.ColumnTime,
 		q.Keyspace,
 		q.Table,
-		q.ColumnID,
+		q.idCondition(),
+		q.bucketClause(),
 		q.ColumnTime,
 		q.ColumnTime,
+		filterClause(q.Filters),
 		perPartitionLimit,
 		allowFiltering,
 	)
@@ -54,3 +105,176 @@ func (q *Query) BuildStatement() string {
 
 	return statement
 }
+
+// BuildAggregateStatement builds cassandra query statement which aggregates values
+// on the server side, grouping them by ID and time buckets of bucketInterval size.
+// Requires Cassandra 4.1+ because of the floor function usage in GROUP BY clause.
+func (q *Query) BuildAggregateStatement() string {
+	var allowFiltering string
+	if q.AllowFiltering {
+		allowFiltering = " ALLOW FILTERING"
+	}
+
+	timeGroup := fmt.Sprintf("floor(%s, %s)", q.ColumnTime, cqlDuration(q.bucketInterval()))
+
+	groupBy := []string{q.ColumnID}
+	if q.BucketColumn != "" {
+		groupBy = append(groupBy, q.BucketColumn)
+	}
+	groupBy = append(groupBy, timeGroup)
+
+	statement := fmt.Sprintf(
+		"SELECT %s, %s, %s AS %s FROM %s.%s WHERE %s%s AND %s >= ? AND %s <= ?%s GROUP BY %s%s",
+		q.ColumnID,
+		strings.Join(q.aggregates(q.Aggregation), ", "),
+		timeGroup,
+		q.ColumnTime,
+		q.Keyspace,
+		q.Table,
+		q.idCondition(),
+		q.bucketClause(),
+		q.ColumnTime,
+		q.ColumnTime,
+		filterClause(q.Filters),
+		strings.Join(groupBy, ", "),
+		allowFiltering,
+	)
+
+	backend.Logger.Debug("Built aggregate statement", "statement", statement)
+
+	return statement
+}
+
+// BuildReduceStatement builds cassandra query statement which reduces values of every ID
+// over the time range with the Reduce function on the server side. Grouping by the partition
+// key is available since Cassandra 3.10, but the statement is only used along with
+// BuildAggregateStatement on Cassandra 4.1+. Time bucketed tables are reduced on the
+// datasource side, as a partition key prefix can't be grouped by.
+func (q *Query) BuildReduceStatement() string {
+	var allowFiltering string
+	if q.AllowFiltering {
+		allowFiltering = " ALLOW FILTERING"
+	}
+
+	statement := fmt.Sprintf(
+		"SELECT %s, %s FROM %s.%s WHERE %s AND %s >= ? AND %s <= ?%s GROUP BY %s%s",
+		q.ColumnID,
+		strings.Join(q.aggregates(q.Reduce), ", "),
+		q.Keyspace,
+		q.Table,
+		q.idCondition(),
+		q.ColumnTime,
+		q.ColumnTime,
+		filterClause(q.Filters),
+		q.ColumnID,
+		allowFiltering,
+	)
+
+	backend.Logger.Debug("Built reduce statement", "statement", statement)
+
+	return statement
+}
+
+// aggregates returns native aggregates of the value columns with the function,
+// aliased with the column names.
+func (q *Query) aggregates(function string) []string {
+	columns := q.valueColumns()
+	aggregates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		value := column
+		if function == aggregationAvg || function == aggregationSum {
+			// cassandra keeps the column type, so avg and sum of integers are
+			// calculated as integers, which may be truncated or overflow
+			value = fmt.Sprintf("cast(%s as double)", value)
+		}
+		aggregates = append(aggregates, fmt.Sprintf("%s(%s) AS %s", function, value, column))
+	}
+
+	return aggregates
+}
+
+// idCondition returns WHERE condition on the ID column. Parallel query
+// is executed for a single ID, so it reads a single partition.
+func (q *Query) idCondition() string {
+	if q.Parallel {
+		return fmt.Sprintf("%s = ?", q.ColumnID)
+	}
+
+	return fmt.Sprintf("%s IN ?", q.ColumnID)
+}
+
+// bucketClause returns WHERE condition on the time bucket column.
+func (q *Query) bucketClause() string {
+	if q.BucketColumn == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" AND %s IN ?", q.BucketColumn)
+}
+
+// valueColumns returns names of all value columns of a strict query.
+func (q *Query) valueColumns() []string {
+	columns := make([]string, 0, len(q.ValueColumns)+1)
+	if q.ColumnValue != "" {
+		columns = append(columns, q.ColumnValue)
+	}
+	for _, c := range q.ValueColumns {
+		columns = append(columns, c.Column)
+	}
+
+	return columns
+}
+
+// columnAliases returns legend alias templates of the value columns which have their own alias.
+func (q *Query) columnAliases() map[string]string {
+	var aliases map[string]string
+	for _, c := range q.ValueColumns {
+		if c.Alias == "" {
+			continue
+		}
+		if aliases == nil {
+			aliases = make(map[string]string)
+		}
+		aliases[c.Column] = c.Alias
+	}
+
+	return aliases
+}
+
+// selectOptions returns query specific paging and limits options.
+func (q *Query) selectOptions() cassandra.SelectOptions {
+	return cassandra.SelectOptions{
+		PageSize: q.PageSize,
+		MaxRows:  q.MaxRows,
+		MaxBytes: q.MaxBytes,
+		NoCache:  q.NoCache,
+	}
+}
+
+// bucketInterval returns downsampling interval, which is the panel interval
+// or larger one if the time range doesn't fit into max data points. The interval
+// is truncated to milliseconds, the precision of cassandra timestamps.
+func (q *Query) bucketInterval() time.Duration {
+	interval := q.Interval
+	if q.MaxDataPoints > 0 {
+		if byPoints := q.TimeTo.Sub(q.TimeFrom) / time.Duration(q.MaxDataPoints); byPoints > interval {
+			interval = byPoints
+		}
+	}
+	interval = interval.Truncate(time.Millisecond)
+	if interval < minBucketInterval {
+		interval = minBucketInterval
+	}
+
+	return interval
+}
+
+// cqlDuration formats the duration as a CQL duration literal in seconds,
+// or in milliseconds when the duration is not a whole number of seconds.
+func cqlDuration(d time.Duration) string {
+	if d%time.Second != 0 {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	return fmt.Sprintf("%ds", d/time.Second)
+}
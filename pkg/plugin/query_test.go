@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -64,6 +65,45 @@ func TestQuery_BuildStatement(t *testing.T) {
 			},
 			want: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ? PER PARTITION LIMIT 1 ALLOW FILTERING",
 		},
+		{
+			name: "with Filters",
+			input: &Query{
+				Keyspace:    "Keyspace",
+				Table:       "Table",
+				ColumnValue: "Value",
+				ColumnID:    "ID",
+				ColumnTime:  "Time",
+				Filters: []Filter{
+					{Column: "region", Operator: "=", Value: "eu"},
+					{Column: "tags", Operator: "contains", Value: "indoor"},
+				},
+			},
+			want: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ? AND region = ? AND tags CONTAINS ?",
+		},
+		{
+			name: "with ValueColumns",
+			input: &Query{
+				Keyspace:     "Keyspace",
+				Table:        "Table",
+				ColumnValue:  "Temperature",
+				ValueColumns: []ValueColumn{{Column: "Humidity"}, {Column: "Pressure", Alias: "pressure"}},
+				ColumnID:     "ID",
+				ColumnTime:   "Time",
+			},
+			want: "SELECT ID, Temperature, Humidity, Pressure, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ?",
+		},
+		{
+			name: "Parallel",
+			input: &Query{
+				Keyspace:    "Keyspace",
+				Table:       "Table",
+				ColumnValue: "Value",
+				ColumnID:    "ID",
+				ColumnTime:  "Time",
+				Parallel:    true,
+			},
+			want: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID = ? AND Time >= ? AND Time <= ?",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -73,3 +113,166 @@ func TestQuery_BuildStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_BuildAggregateStatement(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input *Query
+		want  string
+	}{
+		{
+			name: "min",
+			input: &Query{
+				Keyspace:    "keyspace",
+				Table:       "table",
+				ColumnValue: "value",
+				ColumnID:    "id",
+				ColumnTime:  "time",
+				Aggregation: aggregationMin,
+				Interval:    5 * time.Minute,
+			},
+			want: "SELECT id, min(value) AS value, floor(time, 300s) AS time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id, floor(time, 300s)",
+		},
+		{
+			name: "avg with allow filtering",
+			input: &Query{
+				Keyspace:       "keyspace",
+				Table:          "table",
+				ColumnValue:    "value",
+				ColumnID:       "id",
+				ColumnTime:     "time",
+				Aggregation:    aggregationAvg,
+				Interval:       time.Second,
+				AllowFiltering: true,
+			},
+			want: "SELECT id, avg(cast(value as double)) AS value, floor(time, 1s) AS time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id, floor(time, 1s) ALLOW FILTERING",
+		},
+		{
+			name: "multiple value columns",
+			input: &Query{
+				Keyspace:     "keyspace",
+				Table:        "table",
+				ColumnValue:  "temperature",
+				ValueColumns: []ValueColumn{{Column: "humidity"}},
+				ColumnID:     "id",
+				ColumnTime:   "time",
+				Aggregation:  aggregationMax,
+				Interval:     time.Minute,
+			},
+			want: "SELECT id, max(temperature) AS temperature, max(humidity) AS humidity, floor(time, 60s) AS time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id, floor(time, 60s)",
+		},
+		{
+			name: "time buckets",
+			input: &Query{
+				Keyspace:     "keyspace",
+				Table:        "table",
+				ColumnValue:  "value",
+				ColumnID:     "id",
+				ColumnTime:   "time",
+				Aggregation:  aggregationCount,
+				Interval:     time.Hour,
+				BucketColumn: "day",
+				BucketSize:   BucketDay,
+			},
+			want: "SELECT id, count(value) AS value, floor(time, 3600s) AS time FROM keyspace.table WHERE id IN ? AND day IN ? AND time >= ? AND time <= ? GROUP BY id, day, floor(time, 3600s)",
+		},
+		{
+			name: "fractional seconds",
+			input: &Query{
+				Keyspace:    "keyspace",
+				Table:       "table",
+				ColumnValue: "value",
+				ColumnID:    "id",
+				ColumnTime:  "time",
+				Aggregation: aggregationMax,
+				Interval:    1500 * time.Millisecond,
+			},
+			want: "SELECT id, max(value) AS value, floor(time, 1500ms) AS time FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id, floor(time, 1500ms)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.input.BuildAggregateStatement())
+		})
+	}
+}
+
+func TestQuery_BuildReduceStatement(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input *Query
+		want  string
+	}{
+		{
+			name: "max",
+			input: &Query{
+				Keyspace:    "keyspace",
+				Table:       "table",
+				ColumnValue: "value",
+				ColumnID:    "id",
+				ColumnTime:  "time",
+				Reduce:      aggregationMax,
+			},
+			want: "SELECT id, max(value) AS value FROM keyspace.table WHERE id IN ? AND time >= ? AND time <= ? GROUP BY id",
+		},
+		{
+			name: "sum of multiple value columns in parallel",
+			input: &Query{
+				Keyspace:       "keyspace",
+				Table:          "table",
+				ColumnValue:    "temperature",
+				ValueColumns:   []ValueColumn{{Column: "humidity"}},
+				ColumnID:       "id",
+				ColumnTime:     "time",
+				Reduce:         aggregationSum,
+				Parallel:       true,
+				AllowFiltering: true,
+			},
+			want: "SELECT id, sum(cast(temperature as double)) AS temperature, sum(cast(humidity as double)) AS humidity FROM keyspace.table WHERE id = ? AND time >= ? AND time <= ? GROUP BY id ALLOW FILTERING",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.input.BuildReduceStatement())
+		})
+	}
+}
+
+func TestQuery_bucketInterval(t *testing.T) {
+	from := time.UnixMilli(1257894000000)
+
+	testCases := []struct {
+		name  string
+		input *Query
+		want  time.Duration
+	}{
+		{
+			name:  "empty",
+			input: &Query{},
+			want:  time.Second,
+		},
+		{
+			name:  "panel interval",
+			input: &Query{Interval: time.Minute, MaxDataPoints: 1000, TimeFrom: from, TimeTo: from.Add(time.Hour)},
+			want:  time.Minute,
+		},
+		{
+			name:  "max data points",
+			input: &Query{Interval: time.Second, MaxDataPoints: 100, TimeFrom: from, TimeTo: from.Add(1000 * time.Hour)},
+			want:  10 * time.Hour,
+		},
+		{
+			name:  "milliseconds",
+			input: &Query{Interval: time.Second, MaxDataPoints: 7, TimeFrom: from, TimeTo: from.Add(10 * time.Second)},
+			want:  1428 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.input.bucketInterval())
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// execReducedMetricQuery executes strict query and reduces every series to a single value
+// per value column while rows are streamed from repository. Only the newest row of every
+// partition is fetched for the last value, the same way as for the instant query.
+// Native aggregates are used instead when the cluster supports them.
+func (p *Plugin) execReducedMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	if isNativeAggregation(q.Reduce) && q.BucketColumn == "" && p.supportsGroupByTime(ctx) {
+		return p.execNativeReducedMetricQuery(ctx, q)
+	}
+
+	reduceQuery := *q
+	reduceQuery.Aggregation = q.Reduce
+	agg, err := newAggregator(&reduceQuery)
+	if err != nil {
+		return nil, fmt.Errorf("newAggregator: %w", err)
+	}
+	agg.interval = 0
+
+	if q.Reduce == aggregationLast {
+		reduceQuery.Instant = true
+	}
+
+	values, err := p.strictValues(q)
+	if err != nil {
+		return nil, err
+	}
+
+	notices, err := p.selectStrictFunc(ctx, q, agg.add, reduceQuery.BuildStatement(), values)
+	if err != nil {
+		return nil, fmt.Errorf("selectStrictFunc: %w", err)
+	}
+
+	result := agg.result()
+	result.Notices = notices
+
+	return makeReducedFrames(q, result), nil
+}
+
+// execNativeReducedMetricQuery reduces every series with a native aggregate grouped by ID,
+// so only a single row per ID is transferred.
+func (p *Plugin) execNativeReducedMetricQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	values, err := p.strictValues(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.selectStrict(ctx, q, q.BuildReduceStatement(), values)
+	if err != nil {
+		return nil, fmt.Errorf("selectStrict: %w", err)
+	}
+
+	// aggregates keep the column type, but reduced values are float64 as on the datasource side,
+	// rows are converted to new ones, as rows of the result could be shared by the result cache
+	for id, rows := range result.Rows {
+		reduced := make([]cassandra.Row, 0, len(rows))
+		for _, row := range rows {
+			fields := make(map[string]interface{}, len(row.Fields))
+			for k, v := range row.Fields {
+				fields[k] = v
+			}
+			for _, column := range q.valueColumns() {
+				if v, ok := toFloat64(fields[column]); ok {
+					fields[column] = v
+				}
+			}
+			row.Fields = fields
+			if q.Reduce == aggregationCount {
+				row.Units = nil
+			}
+			reduced = append(reduced, row)
+		}
+		result.Rows[id] = reduced
+	}
+
+	return makeReducedFrames(q, result), nil
+}
+
+// makeReducedFrames creates a numeric frame of a single value for every series and value column,
+// the ID is a label of the value. Frames follow the numeric multi format of grafana dataplane.
+// https://grafana.com/developers/dataplane/numeric#numeric-multi-format
+func makeReducedFrames(q *Query, result *cassandra.Result) data.Frames {
+	columnAliases := q.columnAliases()
+
+	var frames data.Frames
+	for _, id := range result.OrderedIDs() {
+		rows := result.Rows[id]
+		if len(rows) == 0 {
+			continue
+		}
+		row := rows[0]
+
+		for _, column := range q.valueColumns() {
+			var val *float64
+			if v, ok := row.Fields[column].(float64); ok {
+				val = &v
+			}
+
+			field := data.NewField(column, data.Labels{q.ColumnID: id}, []*float64{val})
+			if unit := row.Units[column]; unit != "" {
+				field.SetConfig(&data.FieldConfig{Unit: unit})
+			}
+			alias := q.AliasID
+			if columnAlias, ok := columnAliases[column]; ok {
+				alias = columnAlias
+			}
+			if alias = formatAlias(alias, row.Fields); alias != "" {
+				if field.Config == nil {
+					field.SetConfig(&data.FieldConfig{})
+				}
+				field.Config.DisplayNameFromDS = alias
+			}
+
+			frame := data.NewFrame(id, field)
+			frame.Meta = &data.FrameMeta{Type: data.FrameTypeNumericMulti, TypeVersion: data.FrameTypeVersion{0, 1}}
+			frames = append(frames, frame)
+		}
+	}
+	sortFrames(frames, q.SortBy)
+
+	return appendNotices(frames, result.Notices)
+}
@@ -0,0 +1,220 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestPlugin_ExecQuery_reduce(t *testing.T) {
+	testCases := []struct {
+		function      string
+		wantStatement string
+		want          float64
+	}{
+		{
+			function:      aggregationAvg,
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ?",
+			want:          3,
+		},
+		{
+			function:      aggregationMin,
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ?",
+			want:          1,
+		},
+		{
+			function:      aggregationMax,
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ?",
+			want:          5,
+		},
+		{
+			function:      aggregationCount,
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ?",
+			want:          5,
+		},
+		{
+			function:      aggregationLast,
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ? PER PARTITION LIMIT 1",
+			want:          4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.function, func(t *testing.T) {
+			var statement string
+			repo := &repositoryMock{
+				onSelectFunc: func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+					statement = query
+					for _, row := range aggregationTestRows() {
+						if err := fn("1", row); err != nil {
+							return nil, fmt.Errorf("fn: %w", err)
+						}
+					}
+					return []string{"Result is truncated: limit of 6 rows reached"}, nil
+				},
+				// native aggregates are not grouped by ID before Cassandra 4.1
+				onGetVersion: func(ctx context.Context) (string, error) {
+					return "4.0.11", nil
+				},
+			}
+
+			p := &Plugin{repo: repo}
+			frames, err := p.ExecQuery(context.TODO(), &Query{
+				Keyspace:    "Keyspace",
+				Table:       "Table",
+				ColumnValue: "Value",
+				ColumnID:    "ID",
+				ValueID:     "1",
+				ColumnTime:  "Time",
+				AliasID:     "{{ID}} " + tc.function,
+				TimeFrom:    time.UnixMilli(1257894000000).UTC(),
+				TimeTo:      time.UnixMilli(1257894120000).UTC(),
+				Reduce:      tc.function,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantStatement, statement)
+
+			field := data.NewField("Value", data.Labels{"ID": "1"}, []*float64{pointer(tc.want)})
+			field.SetConfig(&data.FieldConfig{DisplayNameFromDS: "1 " + tc.function})
+			want := data.NewFrame("1", field)
+			want.Meta = &data.FrameMeta{
+				Type:        data.FrameTypeNumericMulti,
+				TypeVersion: data.FrameTypeVersion{0, 1},
+				Notices:     []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 6 rows reached"}},
+			}
+			assert.Equal(t, data.Frames{want}, frames)
+		})
+	}
+}
+
+func TestPlugin_ExecQuery_nativeReduce(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         *Query
+		wantStatement string
+		wantNative    bool
+	}{
+		{
+			name:          "count",
+			query:         &Query{Reduce: aggregationCount},
+			wantStatement: "SELECT ID, count(Value) AS Value FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ? GROUP BY ID",
+			wantNative:    true,
+		},
+		{
+			name:          "last",
+			query:         &Query{Reduce: aggregationLast},
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Time >= ? AND Time <= ? PER PARTITION LIMIT 1",
+		},
+		{
+			name:          "time buckets",
+			query:         &Query{Reduce: aggregationCount, BucketColumn: "Day", BucketSize: BucketDay},
+			wantStatement: "SELECT ID, Value, Time FROM Keyspace.Table WHERE ID IN ? AND Day IN ? AND Time >= ? AND Time <= ?",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var statement string
+			repo := &repositoryMock{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					statement = query
+					return &cassandra.Result{
+						Rows: map[string][]cassandra.Row{"1": {{
+							Columns: []string{"ID", "Value"},
+							Fields:  map[string]interface{}{"ID": "1", "Value": int64(5)},
+							Units:   map[string]string{"Value": "celsius"},
+						}}},
+						IDs: []string{"1"},
+					}, nil
+				},
+				onSelectFunc: func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+					statement = query
+					for _, row := range aggregationTestRows()[:5] {
+						if err := fn("1", row); err != nil {
+							return nil, fmt.Errorf("fn: %w", err)
+						}
+					}
+					return nil, nil
+				},
+				onGetVersion: func(ctx context.Context) (string, error) {
+					return "4.1.3", nil
+				},
+			}
+
+			q := tc.query
+			q.Keyspace, q.Table, q.ColumnValue, q.ColumnID, q.ValueID, q.ColumnTime = "Keyspace", "Table", "Value", "ID", "1", "Time"
+			q.TimeFrom, q.TimeTo = time.UnixMilli(1257894000000).UTC(), time.UnixMilli(1257894120000).UTC()
+
+			p := &Plugin{repo: repo}
+			frames, err := p.ExecQuery(context.TODO(), q)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantStatement, statement)
+			assert.Len(t, frames, 1)
+			if tc.wantNative {
+				field := data.NewField("Value", data.Labels{"ID": "1"}, []*float64{pointer(5.0)})
+				want := data.NewFrame("1", field)
+				want.Meta = &data.FrameMeta{Type: data.FrameTypeNumericMulti, TypeVersion: data.FrameTypeVersion{0, 1}}
+				assert.Equal(t, data.Frames{want}, frames)
+			}
+		})
+	}
+}
+
+func TestPlugin_ExecQuery_nativeReduceCached(t *testing.T) {
+	repo := &repositoryMock{
+		onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+			result := &cassandra.Result{}
+			result.Add("1", cassandra.Row{
+				Columns: []string{"ID", "Value"},
+				Fields:  map[string]interface{}{"ID": "1", "Value": int64(5)},
+			})
+			return result, nil
+		},
+		onGetVersion: func(ctx context.Context) (string, error) {
+			return "4.1.3", nil
+		},
+	}
+
+	// queries share rows of the cached result
+	p := New(repo, Options{CacheTTL: time.Minute})
+	query := func() (data.Frames, error) {
+		return p.ExecQuery(context.TODO(), &Query{
+			Keyspace:    "Keyspace",
+			Table:       "Table",
+			ColumnValue: "Value",
+			ColumnID:    "ID",
+			ValueID:     "1",
+			ColumnTime:  "Time",
+			TimeFrom:    time.UnixMilli(1257894000000).UTC(),
+			TimeTo:      time.UnixMilli(1257894120000).UTC(),
+			Reduce:      aggregationMax,
+		})
+	}
+	_, err := query()
+	assert.NoError(t, err)
+
+	var group errgroup.Group
+	for range 8 {
+		group.Go(func() error {
+			frames, err := query()
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, pointer(5.0), frames[0].Fields[0].At(0))
+			return nil
+		})
+	}
+	assert.NoError(t, group.Wait())
+}
+
+func TestPlugin_ExecQuery_reduceUnsupported(t *testing.T) {
+	p := &Plugin{repo: &repositoryMock{}}
+	_, err := p.ExecQuery(context.TODO(), &Query{ColumnValue: "Value", ColumnID: "ID", ValueID: "1", ColumnTime: "Time", Reduce: "median"})
+	assert.EqualError(t, err, "query processing: newAggregator: unsupported aggregation: median")
+}
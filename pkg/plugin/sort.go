@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"math"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Series sort orders, series keep the order in which they are returned by the query by default.
+const (
+	sortByID    = "id"
+	sortByAlias = "alias"
+	// sortByLast orders series by the last value, the highest first.
+	sortByLast = "last"
+)
+
+// sortFrames sorts frames in place. Sorting is stable, so frames with equal keys keep
+// the query order. Empty frames and frames without the sort key are placed last.
+func sortFrames(frames data.Frames, sortBy string) {
+	switch sortBy {
+	case sortByID:
+		sort.SliceStable(frames, func(i, j int) bool {
+			if frames[i] == nil || frames[j] == nil {
+				return frames[j] == nil && frames[i] != nil
+			}
+			return frames[i].Name < frames[j].Name
+		})
+	case sortByAlias:
+		sort.SliceStable(frames, func(i, j int) bool {
+			if frames[i] == nil || frames[j] == nil {
+				return frames[j] == nil && frames[i] != nil
+			}
+			return frameAlias(frames[i]) < frameAlias(frames[j])
+		})
+	case sortByLast:
+		sort.SliceStable(frames, func(i, j int) bool {
+			vi, vj := frameLastValue(frames[i]), frameLastValue(frames[j])
+			if math.IsNaN(vi) || math.IsNaN(vj) {
+				return math.IsNaN(vj) && !math.IsNaN(vi)
+			}
+			return vi > vj
+		})
+	}
+}
+
+// frameAlias returns the display name of the first numeric field, or the frame name when it has no alias.
+func frameAlias(frame *data.Frame) string {
+	if field := firstNumericField(frame); field != nil && field.Config != nil && field.Config.DisplayNameFromDS != "" {
+		return field.Config.DisplayNameFromDS
+	}
+
+	return frame.Name
+}
+
+// frameLastValue returns the last non-NULL value of the first numeric field, NaN when there is none.
+func frameLastValue(frame *data.Frame) float64 {
+	field := firstNumericField(frame)
+	if field == nil {
+		return math.NaN()
+	}
+	for i := field.Len() - 1; i >= 0; i-- {
+		if val, err := field.FloatAt(i); err == nil && !math.IsNaN(val) {
+			return val
+		}
+	}
+
+	return math.NaN()
+}
+
+func firstNumericField(frame *data.Frame) *data.Field {
+	if frame == nil {
+		return nil
+	}
+	for _, field := range frame.Fields {
+		if field.Type().Numeric() {
+			return field
+		}
+	}
+
+	return nil
+}
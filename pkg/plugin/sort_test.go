@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sortFrames(t *testing.T) {
+	makeFrame := func(id, alias string, values ...*float64) *data.Frame {
+		field := data.NewField("value", nil, values)
+		if alias != "" {
+			field.SetConfig(&data.FieldConfig{DisplayNameFromDS: alias})
+		}
+		return data.NewFrame(id, data.NewField("name", nil, make([]string, len(values))), field)
+	}
+
+	testCases := []struct {
+		name   string
+		sortBy string
+		frames data.Frames
+		want   []string
+	}{
+		{
+			name:   "query order",
+			frames: data.Frames{makeFrame("b", ""), makeFrame("a", "")},
+			want:   []string{"b", "a"},
+		},
+		{
+			name:   "id",
+			sortBy: "id",
+			frames: data.Frames{makeFrame("b", ""), nil, makeFrame("a", "")},
+			want:   []string{"a", "b", ""},
+		},
+		{
+			name:   "alias",
+			sortBy: "alias",
+			frames: data.Frames{makeFrame("1", "zeta"), makeFrame("2", "alpha"), makeFrame("beta", "")},
+			want:   []string{"2", "beta", "1"},
+		},
+		{
+			name:   "last value",
+			sortBy: "last",
+			frames: data.Frames{
+				makeFrame("low", "", pointer(5.0), pointer(1.0)),
+				makeFrame("empty", "", nil),
+				makeFrame("high", "", pointer(1.0), pointer(7.0), nil),
+				makeFrame("equal", "", pointer(1.0)),
+			},
+			want: []string{"high", "low", "equal", "empty"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sortFrames(tc.frames, tc.sortBy)
+
+			names := make([]string, 0, len(tc.frames))
+			for _, frame := range tc.frames {
+				if frame == nil {
+					names = append(names, "")
+					continue
+				}
+				names = append(names, frame.Name)
+			}
+			assert.Equal(t, tc.want, names)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// formatTable is a query result format of a single frame with all the rows,
+// the default format is a time series frame per ID.
+const formatTable = "table"
+
+// execTableQuery executes raw or strict query and returns all rows as a single
+// frame, columns and rows are kept in the order returned by the cluster. Rows of
+// a strict query are grouped by ID, rows of a parallel or time bucketed query come
+// from several queries or partitions, so they are sorted by time like time series.
+func (p *Plugin) execTableQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	var (
+		rows    []cassandra.Row
+		notices []string
+	)
+	if q.RawQuery {
+		appendRow := func(_ string, row cassandra.Row) error {
+			rows = append(rows, row)
+			return nil
+		}
+
+		var err error
+		notices, err = p.repo.SelectFunc(ctx, q.selectOptions(), appendRow, q.Target, q.Values...)
+		if err != nil {
+			return nil, fmt.Errorf("repo.SelectFunc: %w", err)
+		}
+	} else {
+		if q.Aggregation != "" {
+			return nil, errors.New("table format doesn't support aggregation")
+		}
+		if q.Reduce != "" {
+			return nil, errors.New("table format doesn't support reduce")
+		}
+
+		result, err := p.selectStrictRows(ctx, q, q.BuildStatement())
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range result.OrderedIDs() {
+			rows = append(rows, result.Rows[id]...)
+		}
+		notices = result.Notices
+	}
+
+	frame := makeDataFrameFromRows("", "", nil, rows)
+	if frame == nil {
+		return appendNotices(nil, notices), nil
+	}
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+
+	return appendNotices(data.Frames{frame}, notices), nil
+}
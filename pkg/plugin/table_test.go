@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_table(t *testing.T) {
+	t1 := time.UnixMilli(1257894000000).UTC()
+	t2 := time.UnixMilli(1257894001000).UTC()
+	columns := []string{"time", "sensor_id", "value"}
+	rows := []cassandra.Row{
+		{Columns: columns, Fields: map[string]interface{}{"time": t2, "sensor_id": "2", "value": 2.0}},
+		{Columns: columns, Fields: map[string]interface{}{"time": t1, "sensor_id": "1", "value": nil}},
+		{Columns: columns, Fields: map[string]interface{}{"time": t1, "sensor_id": "2", "value": 1.0}},
+	}
+
+	var statement string
+	repo := &repositoryMock{
+		onSelectFunc: func(ctx context.Context, opts cassandra.SelectOptions, fn cassandra.RowFunc, query string, values ...interface{}) ([]string, error) {
+			statement = query
+			for _, row := range rows {
+				if err := fn(row.Fields["sensor_id"].(string), row); err != nil {
+					return nil, err
+				}
+			}
+			return []string{"Result is truncated: limit of 3 rows reached"}, nil
+		},
+	}
+
+	want := data.NewFrame("",
+		data.NewField("time", nil, []time.Time{t2, t1, t1}),
+		data.NewField("sensor_id", nil, []string{"2", "1", "2"}),
+		data.NewField("value", nil, []*float64{pointer(2.0), nil, pointer(1.0)}),
+	)
+	want.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeTable,
+		Notices:                []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 3 rows reached"}},
+	}
+
+	p := &Plugin{repo: repo}
+	t.Run("raw query", func(t *testing.T) {
+		frames, err := p.ExecQuery(context.TODO(), &Query{
+			RawQuery: true,
+			Target:   "SELECT time, sensor_id, value FROM ks.events LIMIT 50",
+			Format:   "table",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT time, sensor_id, value FROM ks.events LIMIT 50", statement)
+		assert.Equal(t, data.Frames{want}, frames)
+	})
+
+	t.Run("aggregation", func(t *testing.T) {
+		_, err := p.ExecQuery(context.TODO(), &Query{
+			ColumnValue: "value",
+			ColumnID:    "sensor_id",
+			ValueID:     "1",
+			ColumnTime:  "time",
+			Aggregation: aggregationAvg,
+			Format:      "table",
+		})
+		assert.EqualError(t, err, "query processing: table format doesn't support aggregation")
+	})
+
+	t.Run("reduce", func(t *testing.T) {
+		_, err := p.ExecQuery(context.TODO(), &Query{
+			ColumnValue: "value",
+			ColumnID:    "sensor_id",
+			ValueID:     "1",
+			ColumnTime:  "time",
+			Reduce:      aggregationLast,
+			Format:      "table",
+		})
+		assert.EqualError(t, err, "query processing: table format doesn't support reduce")
+	})
+}
+
+func TestPlugin_ExecQuery_tableStrict(t *testing.T) {
+	t1 := time.UnixMilli(1257894000000).UTC()
+	t2 := time.UnixMilli(1257894001000).UTC()
+	columns := []string{"sensor_id", "value", "time"}
+	idRows := map[string][]cassandra.Row{
+		"1": {{Columns: columns, Fields: map[string]interface{}{"sensor_id": "1", "value": nil, "time": t1}}},
+		"2": {
+			{Columns: columns, Fields: map[string]interface{}{"sensor_id": "2", "value": 2.0, "time": t2}},
+			{Columns: columns, Fields: map[string]interface{}{"sensor_id": "2", "value": 1.0, "time": t1}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		parallel bool
+		want     *data.Frame
+	}{
+		{
+			name: "IN query",
+			want: data.NewFrame("",
+				data.NewField("sensor_id", nil, []string{"2", "2", "1"}),
+				data.NewField("value", nil, []*float64{pointer(2.0), pointer(1.0), nil}),
+				data.NewField("time", nil, []time.Time{t2, t1, t1}),
+			),
+		},
+		{
+			name:     "parallel",
+			parallel: true,
+			want: data.NewFrame("",
+				data.NewField("sensor_id", nil, []string{"1", "2", "2"}),
+				data.NewField("value", nil, []*float64{nil, pointer(1.0), pointer(2.0)}),
+				data.NewField("time", nil, []time.Time{t1, t1, t2}),
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &repositoryMock{
+				onSelect: func(ctx context.Context, opts cassandra.SelectOptions, query string, values ...interface{}) (*cassandra.Result, error) {
+					result := &cassandra.Result{}
+					ids := values[0]
+					if id, ok := ids.(string); ok {
+						ids = []string{id}
+					}
+					// the IN query returns partitions in the token order
+					for _, id := range []string{"2", "1"} {
+						if !slices.Contains(ids.([]string), id) {
+							continue
+						}
+						for _, row := range idRows[id] {
+							result.Add(id, row)
+						}
+					}
+					return result, nil
+				},
+			}
+
+			p := &Plugin{repo: repo}
+			frames, err := p.ExecQuery(context.TODO(), &Query{
+				Keyspace:    "ks",
+				Table:       "events",
+				ColumnValue: "value",
+				ColumnID:    "sensor_id",
+				ValueID:     "1,2",
+				ColumnTime:  "time",
+				Parallel:    tc.parallel,
+				Format:      "table",
+			})
+			assert.NoError(t, err)
+
+			tc.want.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+			assert.Equal(t, data.Frames{tc.want}, frames)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultTraceTable is the spans table of the Jaeger Cassandra schema.
+const defaultTraceTable = "traces"
+
+// traceKeyValue is a tag of the grafana trace frame.
+type traceKeyValue struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// traceLog is a span log of the grafana trace frame.
+type traceLog struct {
+	Timestamp float64         `json:"timestamp"`
+	Fields    []traceKeyValue `json:"fields"`
+}
+
+// execTraceQuery reads spans of the trace stored with the Jaeger Cassandra schema
+// and transforms them to a grafana trace frame.
+func (p *Plugin) execTraceQuery(ctx context.Context, q *Query) (data.Frames, error) {
+	if q.Keyspace == "" {
+		return nil, errors.New("keyspace is required")
+	}
+	traceID, err := parseTraceID(q.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("parseTraceID: %w", err)
+	}
+
+	table := q.Table
+	if table == "" {
+		table = defaultTraceTable
+	}
+
+	spans, err := p.repo.GetTraceSpans(ctx, q.Keyspace, table, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("repo.GetTraceSpans: %w", err)
+	}
+	if len(spans) == 0 {
+		return nil, nil
+	}
+
+	frame, err := makeTraceFrame(spans)
+	if err != nil {
+		return nil, fmt.Errorf("makeTraceFrame: %w", err)
+	}
+
+	return data.Frames{frame}, nil
+}
+
+// parseTraceID converts hex trace ID of up to 32 digits to 16 bytes stored by Jaeger.
+func parseTraceID(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("trace ID is required")
+	}
+	if len(s) > 32 {
+		return nil, fmt.Errorf("trace ID %s is too long", s)
+	}
+
+	traceID, err := hex.DecodeString(strings.Repeat("0", 32-len(s)) + s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace ID %s: %w", s, err)
+	}
+
+	return traceID, nil
+}
+
+// formatTraceID formats trace ID the same way as Jaeger does,
+// the high 8 bytes are omitted when they are zero.
+func formatTraceID(traceID []byte) string {
+	if len(traceID) == 16 && bytes.Equal(traceID[:8], make([]byte, 8)) {
+		traceID = traceID[8:]
+	}
+
+	return hex.EncodeToString(traceID)
+}
+
+func formatSpanID(spanID int64) string {
+	return fmt.Sprintf("%016x", uint64(spanID))
+}
+
+// parentSpanID returns the span parent, which is either set explicitly
+// or is the first CHILD_OF reference to a span of the same trace.
+func parentSpanID(span cassandra.Span) string {
+	if span.ParentID != 0 {
+		return formatSpanID(span.ParentID)
+	}
+	for _, ref := range span.Refs {
+		if ref.RefType == "child-of" && bytes.Equal(ref.TraceID, span.TraceID) {
+			return formatSpanID(ref.SpanID)
+		}
+	}
+
+	return ""
+}
+
+// makeTraceFrame creates a frame of the grafana trace format,
+// spans are ordered by the start time.
+// https://grafana.com/docs/grafana/latest/explore/trace-integration/#data-api
+func makeTraceFrame(spans []cassandra.Span) (*data.Frame, error) {
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime < spans[j].StartTime
+	})
+
+	var (
+		traceIDs       = make([]string, 0, len(spans))
+		spanIDs        = make([]string, 0, len(spans))
+		parentSpanIDs  = make([]string, 0, len(spans))
+		operationNames = make([]string, 0, len(spans))
+		serviceNames   = make([]string, 0, len(spans))
+		serviceTags    = make([]json.RawMessage, 0, len(spans))
+		startTimes     = make([]float64, 0, len(spans))
+		durations      = make([]float64, 0, len(spans))
+		logs           = make([]json.RawMessage, 0, len(spans))
+		tags           = make([]json.RawMessage, 0, len(spans))
+	)
+	for _, span := range spans {
+		traceIDs = append(traceIDs, formatTraceID(span.TraceID))
+		spanIDs = append(spanIDs, formatSpanID(span.SpanID))
+		parentSpanIDs = append(parentSpanIDs, parentSpanID(span))
+		operationNames = append(operationNames, span.OperationName)
+		serviceNames = append(serviceNames, span.Process.ServiceName)
+		// microseconds to milliseconds
+		startTimes = append(startTimes, float64(span.StartTime)/1000)
+		durations = append(durations, float64(span.Duration)/1000)
+
+		spanLogs := make([]traceLog, 0, len(span.Logs))
+		for _, log := range span.Logs {
+			spanLogs = append(spanLogs, traceLog{Timestamp: float64(log.Timestamp) / 1000, Fields: traceKeyValues(log.Fields)})
+		}
+
+		spanServiceTags, err := json.Marshal(traceKeyValues(span.Process.Tags))
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		serviceTags = append(serviceTags, spanServiceTags)
+
+		spanLogsJSON, err := json.Marshal(spanLogs)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		logs = append(logs, spanLogsJSON)
+
+		spanTags, err := json.Marshal(traceKeyValues(span.Tags))
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal: %w", err)
+		}
+		tags = append(tags, spanTags)
+	}
+
+	frame := data.NewFrame("trace",
+		data.NewField("traceID", nil, traceIDs),
+		data.NewField("spanID", nil, spanIDs),
+		data.NewField("parentSpanID", nil, parentSpanIDs),
+		data.NewField("operationName", nil, operationNames),
+		data.NewField("serviceName", nil, serviceNames),
+		data.NewField("serviceTags", nil, serviceTags),
+		data.NewField("startTime", nil, startTimes),
+		data.NewField("duration", nil, durations),
+		data.NewField("logs", nil, logs),
+		data.NewField("tags", nil, tags),
+	)
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTrace}
+
+	return frame, nil
+}
+
+func traceKeyValues(kvs []cassandra.KeyValue) []traceKeyValue {
+	result := make([]traceKeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		result = append(result, traceKeyValue{Key: kv.Key, Value: kv.Value()})
+	}
+
+	return result
+}
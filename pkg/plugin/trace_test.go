@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_ExecQuery_trace(t *testing.T) {
+	traceID := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x6b, 0x7c, 0x9a, 0x1e, 0x2f, 0x3d, 0x4c, 0x5b}
+	process := cassandra.Process{
+		ServiceName: "api",
+		Tags:        []cassandra.KeyValue{{Key: "hostname", ValueType: "string", ValueString: "host-1"}},
+	}
+
+	repo := &repositoryMock{
+		onGetTraceSpans: func(ctx context.Context, keyspace, table string, id []byte) ([]cassandra.Span, error) {
+			assert.Equal(t, "jaeger_v1_dc1", keyspace)
+			assert.Equal(t, "traces", table)
+			assert.Equal(t, traceID, id)
+
+			return []cassandra.Span{
+				{
+					TraceID:       traceID,
+					SpanID:        2,
+					OperationName: "SELECT",
+					StartTime:     1257894000500000,
+					Duration:      1500,
+					Tags:          []cassandra.KeyValue{{Key: "db.rows", ValueType: "int64", ValueLong: 10}},
+					Refs:          []cassandra.SpanRef{{RefType: "child-of", TraceID: traceID, SpanID: 1}},
+					Process:       process,
+				},
+				{
+					TraceID:       traceID,
+					SpanID:        1,
+					OperationName: "GET /users",
+					StartTime:     1257894000000000,
+					Duration:      2000000,
+					Tags:          []cassandra.KeyValue{{Key: "error", ValueType: "bool", ValueBool: true}},
+					Logs: []cassandra.SpanLog{{
+						Timestamp: 1257894001000000,
+						Fields:    []cassandra.KeyValue{{Key: "event", ValueType: "string", ValueString: "timeout"}},
+					}},
+					Process: process,
+				},
+			}, nil
+		},
+	}
+
+	p := &Plugin{repo: repo}
+	frames, err := p.ExecQuery(context.TODO(), &Query{
+		Keyspace:     "jaeger_v1_dc1",
+		TraceID:      "6B7C9A1E2F3D4C5B",
+		IsTraceQuery: true,
+	})
+	assert.NoError(t, err)
+
+	serviceTags := json.RawMessage(`[{"key":"hostname","value":"host-1"}]`)
+	want := data.NewFrame("trace",
+		data.NewField("traceID", nil, []string{"6b7c9a1e2f3d4c5b", "6b7c9a1e2f3d4c5b"}),
+		data.NewField("spanID", nil, []string{"0000000000000001", "0000000000000002"}),
+		data.NewField("parentSpanID", nil, []string{"", "0000000000000001"}),
+		data.NewField("operationName", nil, []string{"GET /users", "SELECT"}),
+		data.NewField("serviceName", nil, []string{"api", "api"}),
+		data.NewField("serviceTags", nil, []json.RawMessage{serviceTags, serviceTags}),
+		data.NewField("startTime", nil, []float64{1257894000000, 1257894000500}),
+		data.NewField("duration", nil, []float64{2000, 1.5}),
+		data.NewField("logs", nil, []json.RawMessage{
+			json.RawMessage(`[{"timestamp":1257894001000,"fields":[{"key":"event","value":"timeout"}]}]`),
+			json.RawMessage(`[]`),
+		}),
+		data.NewField("tags", nil, []json.RawMessage{
+			json.RawMessage(`[{"key":"error","value":true}]`),
+			json.RawMessage(`[{"key":"db.rows","value":10}]`),
+		}),
+	)
+	want.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTrace}
+	assert.Equal(t, data.Frames{want}, frames)
+}
+
+func Test_parseTraceID(t *testing.T) {
+	testCases := []struct {
+		name    string
+		traceID string
+		want    []byte
+		wantErr string
+	}{
+		{
+			name:    "128 bit",
+			traceID: "0102030405060708090a0b0c0d0e0f10",
+			want:    []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		},
+		{
+			name:    "short",
+			traceID: "abc",
+			want:    []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x0a, 0xbc},
+		},
+		{
+			name:    "empty",
+			traceID: " ",
+			wantErr: "trace ID is required",
+		},
+		{
+			name:    "too long",
+			traceID: "0102030405060708090a0b0c0d0e0f1011",
+			wantErr: "trace ID 0102030405060708090a0b0c0d0e0f1011 is too long",
+		},
+		{
+			name:    "not hex",
+			traceID: "xyz",
+			wantErr: "invalid trace ID xyz: encoding/hex: invalid byte: U+0078 'x'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTraceID(tc.traceID)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// formatWide is a query result format of a single wide time series frame.
+const formatWide = "wide"
+
+// labeledRow is a row with labels of the expanded map columns.
+type labeledRow struct {
+	id     string
+	labels data.Labels
+	row    cassandra.Row
+}
+
+// makeWideFrames converts the result to a long time series frame and then to a single wide frame
+// with a shared time field, see data.LongToWide. The ID, text and other non-numeric columns are
+// the dimensions: their values of every row become labels, so a series is split when they change.
+// Numeric columns are the values, missing points of a series are NULLs.
+func makeWideFrames(q *Query, result *cassandra.Result) (data.Frames, error) {
+	var points []labeledRow
+	for _, id := range result.OrderedIDs() {
+		groups := []labeledRows{{rows: result.Rows[id]}}
+		if q.ExpandCollections {
+			groups = expandCollections(result.Rows[id])
+		}
+		for _, group := range groups {
+			for _, row := range group.rows {
+				points = append(points, labeledRow{id: id, labels: group.labels, row: row})
+			}
+		}
+	}
+	if len(points) == 0 {
+		return appendNotices(nil, result.Notices), nil
+	}
+
+	rows := make([]cassandra.Row, 0, len(points))
+	for _, r := range points {
+		rows = append(rows, r.row)
+	}
+	columns := rows[0].Columns
+
+	timeColumn, err := firstTimeColumn(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// rows without time can't be placed to the time series
+	filtered := points[:0]
+	for _, r := range points {
+		if _, ok := r.row.Fields[timeColumn].(time.Time); ok {
+			filtered = append(filtered, r)
+		}
+	}
+	points = filtered
+	if len(points) == 0 {
+		return appendNotices(nil, result.Notices), nil
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].row.Fields[timeColumn].(time.Time).Before(points[j].row.Fields[timeColumn].(time.Time))
+	})
+
+	// the first column is the ID, which is a dimension regardless of its type
+	times := make([]time.Time, 0, len(points))
+	longFields := []*data.Field{data.NewField(timeColumn, nil, times)}
+	var dimensions, values []string
+	for i, colName := range columns {
+		switch {
+		case colName == timeColumn:
+			continue
+		case i > 0 && columnFieldType(colName, rows).Numeric():
+			values = append(values, colName)
+		default:
+			dimensions = append(dimensions, colName)
+		}
+	}
+
+	var labelNames []string
+	seen := make(map[string]bool)
+	for _, colName := range columns {
+		seen[colName] = true
+	}
+	for _, r := range points {
+		for name := range r.labels {
+			if !seen[name] {
+				seen[name] = true
+				labelNames = append(labelNames, name)
+			}
+		}
+	}
+	sort.Strings(labelNames)
+
+	dimensionValues := make([][]string, len(dimensions)+len(labelNames))
+	valueFields := make([]*data.Field, 0, len(values))
+	for _, colName := range values {
+		field := data.NewFieldFromFieldType(columnFieldType(colName, rows), len(points))
+		field.Name = colName
+		valueFields = append(valueFields, field)
+	}
+	for i, r := range points {
+		longFields[0].Append(r.row.Fields[timeColumn])
+		for j, colName := range dimensions {
+			val := textValue(r.row.Fields[colName])
+			if j == 0 && colName == columns[0] {
+				val = r.id
+			}
+			dimensionValues[j] = append(dimensionValues[j], val)
+		}
+		for j, name := range labelNames {
+			dimensionValues[len(dimensions)+j] = append(dimensionValues[len(dimensions)+j], r.labels[name])
+		}
+		for j, colName := range values {
+			if val := r.row.Fields[colName]; val != nil {
+				valueFields[j].SetConcrete(i, val)
+			}
+		}
+	}
+	for i, name := range append(dimensions, labelNames...) {
+		longFields = append(longFields, data.NewField(name, nil, dimensionValues[i]))
+	}
+	longFields = append(longFields, valueFields...)
+
+	frame := data.NewFrame("", longFields...)
+	if len(dimensions)+len(labelNames) > 0 {
+		frame, err = data.LongToWide(frame, &data.FillMissing{Mode: data.FillModeNull})
+		if err != nil {
+			return nil, fmt.Errorf("data.LongToWide: %w", err)
+		}
+	} else {
+		frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide, TypeVersion: data.FrameTypeVersion{0, 1}}
+	}
+
+	// aliases are interpolated with labels of the series
+	columnAliases := q.columnAliases()
+	for _, field := range frame.Fields[1:] {
+		if unit := rows[0].Units[field.Name]; unit != "" {
+			field.SetConfig(&data.FieldConfig{Unit: unit})
+		}
+		alias := q.AliasID
+		if columnAlias, ok := columnAliases[field.Name]; ok {
+			alias = columnAlias
+		}
+		labels := make(map[string]interface{}, len(field.Labels))
+		for k, v := range field.Labels {
+			labels[k] = v
+		}
+		if alias = formatAlias(alias, labels); alias != "" {
+			if field.Config == nil {
+				field.SetConfig(&data.FieldConfig{})
+			}
+			field.Config.DisplayNameFromDS = alias
+		}
+	}
+
+	return appendNotices(data.Frames{frame}, result.Notices), nil
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HadesArchitect/GrafanaCassandraDatasource/pkg/cassandra"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_makeWideFrames(t *testing.T) {
+	t1 := time.UnixMilli(1257894000000).UTC()
+	t2 := time.UnixMilli(1257894001000).UTC()
+	t3 := time.UnixMilli(1257894002000).UTC()
+	columns := []string{"id", "host", "value", "time"}
+	row := func(id int64, host string, value float64, ts time.Time) cassandra.Row {
+		return cassandra.Row{
+			Columns: columns,
+			Fields:  map[string]interface{}{"id": id, "host": host, "value": value, "time": ts},
+			Units:   map[string]string{"value": "percent"},
+		}
+	}
+
+	result := &cassandra.Result{
+		Rows: map[string][]cassandra.Row{
+			"1": {row(1, "a", 1, t1), row(1, "b", 2, t3)},
+			"2": {row(2, "a", 3, t2)},
+		},
+		IDs:     []string{"1", "2"},
+		Notices: []string{"Result is truncated: limit of 3 rows reached"},
+	}
+
+	frames, err := makeWideFrames(&Query{Format: "wide", AliasID: "{{id}} at {{host}}"}, result)
+	assert.NoError(t, err)
+
+	makeField := func(labels data.Labels, values ...*float64) *data.Field {
+		field := data.NewField("value", labels, values)
+		field.SetConfig(&data.FieldConfig{Unit: "percent", DisplayNameFromDS: labels["id"] + " at " + labels["host"]})
+		return field
+	}
+	want := data.NewFrame("",
+		data.NewField("time", nil, []time.Time{t1, t2, t3}),
+		makeField(data.Labels{"id": "1", "host": "a"}, pointer(1.0), nil, nil),
+		makeField(data.Labels{"id": "1", "host": "b"}, nil, nil, pointer(2.0)),
+		makeField(data.Labels{"id": "2", "host": "a"}, nil, pointer(3.0), nil),
+	)
+	want.Meta = &data.FrameMeta{
+		Type:        data.FrameTypeTimeSeriesWide,
+		TypeVersion: data.FrameTypeVersion{0, 1},
+		Notices:     []data.Notice{{Severity: data.NoticeSeverityWarning, Text: "Result is truncated: limit of 3 rows reached"}},
+	}
+	assert.Equal(t, data.Frames{want}, frames)
+}
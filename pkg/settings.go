@@ -24,6 +24,14 @@ type dataSourceSettings struct {
 	UseCustomTLS          bool   `json:"UseCustomTLS"`
 	AllowInsecureTLS      bool   `json:"allowInsecureTLS"`
 	AllowedAuthenticators string `json:"allowedAuthenticators"`
+	ExactNumbers          bool   `json:"exactNumbers"`
+	PageSize              int    `json:"pageSize"`
+	MaxRows               int    `json:"maxRows"`
+	MaxBytes              int    `json:"maxBytes"`
+	ConcurrentQueries     int    `json:"concurrentQueries"`
+	CacheTTL              int    `json:"cacheTTL"`
+	CacheSize             int    `json:"cacheSize"`
+	CommentFieldConfigs   bool   `json:"commentFieldConfigs"`
 }
 
 // parseAllowedAuthenticators splits the semicolon-separated allowedAuthenticators
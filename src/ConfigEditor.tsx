@@ -94,6 +94,33 @@ export class ConfigEditor extends PureComponent<Props, State> {
     onOptionsChange({ ...options, jsonData });
   };
 
+  onExactNumbersChange = (event: React.FormEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      exactNumbers: event.currentTarget.checked,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  onCommentFieldConfigsChange = (event: React.FormEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      commentFieldConfigs: event.currentTarget.checked,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  onLimitChange = (key: 'pageSize' | 'maxRows' | 'maxBytes' | 'concurrentQueries' | 'cacheTTL' | 'cacheSize') => (event: ChangeEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      [key]: Number(event.target.value),
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
   onUseCustomTLSChange = (event: React.FormEvent<HTMLInputElement>) => {
     const { onOptionsChange, options } = this.props;
     const jsonData = {
@@ -295,6 +322,118 @@ export class ConfigEditor extends PureComponent<Props, State> {
             </InlineField>
           </InlineFieldRow>
         </FieldSet>
+        <FieldSet label="Query settings">
+          <InlineFieldRow>
+            <InlineField
+              label="Exact numbers"
+              labelWidth={25}
+              tooltip="Return decimal and varint values as strings to keep their exact precision. By default they are converted to numbers"
+            >
+              <InlineSwitch value={options.jsonData.exactNumbers} onChange={this.onExactNumbersChange} />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Field config from comments"
+              labelWidth={25}
+              tooltip="Set units, display names, decimals, min, max and thresholds of the columns from the grafana: JSON in the table comment"
+            >
+              <InlineSwitch value={options.jsonData.commentFieldConfigs} onChange={this.onCommentFieldConfigsChange} />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField label="Page size" labelWidth={25} tooltip="Number of rows fetched from Cassandra in a single page. Keep empty for the default value">
+              <Input
+                name="pageSize"
+                type="number"
+                step={1}
+                value={options.jsonData.pageSize}
+                onChange={this.onLimitChange('pageSize')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Max rows"
+              labelWidth={25}
+              tooltip="Maximum number of rows returned by a single query, the rest of the result is dropped. Keep empty for no limit"
+            >
+              <Input
+                name="maxRows"
+                type="number"
+                step={1}
+                value={options.jsonData.maxRows}
+                onChange={this.onLimitChange('maxRows')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Max bytes"
+              labelWidth={25}
+              tooltip="Maximum approximate size of data returned by a single query, the rest of the result is dropped. Keep empty for no limit"
+            >
+              <Input
+                name="maxBytes"
+                type="number"
+                step={1}
+                value={options.jsonData.maxBytes}
+                onChange={this.onLimitChange('maxBytes')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Concurrent queries"
+              labelWidth={25}
+              tooltip="Maximum number of queries executed at once by the datasource, shared by all panels and alerts. Keep empty for the default value of 5"
+            >
+              <Input
+                name="concurrentQueries"
+                type="number"
+                step={1}
+                value={options.jsonData.concurrentQueries}
+                onChange={this.onLimitChange('concurrentQueries')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Cache TTL"
+              labelWidth={25}
+              tooltip="Time in seconds query results are cached for, queries of time ranges within the same periods share the result. Keep empty to disable the cache"
+            >
+              <Input
+                name="cacheTTL"
+                type="number"
+                step={1}
+                value={options.jsonData.cacheTTL}
+                onChange={this.onLimitChange('cacheTTL')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+          <InlineFieldRow>
+            <InlineField
+              label="Cache size"
+              labelWidth={25}
+              tooltip="Maximum number of cached query results. Keep empty for the default value of 1000"
+            >
+              <Input
+                name="cacheSize"
+                type="number"
+                step={1}
+                value={options.jsonData.cacheSize}
+                onChange={this.onLimitChange('cacheSize')}
+                width={60}
+              />
+            </InlineField>
+          </InlineFieldRow>
+        </FieldSet>
         <FieldSet label="TLS Settings">
           <InlineFieldRow>
             <InlineField
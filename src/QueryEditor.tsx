@@ -1,11 +1,61 @@
 import React, { ChangeEvent, PureComponent, FormEvent } from 'react';
-import { InlineField, InlineFieldRow, Input, InlineSwitch, LinkButton, RadioButtonGroup, Select, TextArea } from '@grafana/ui';
+import { Button, IconButton, InlineField, InlineFieldRow, Input, InlineSwitch, LinkButton, RadioButtonGroup, Select, TextArea } from '@grafana/ui';
 import { CoreApp, QueryEditorProps, SelectableValue } from '@grafana/data';
 import { CassandraDatasource } from './datasource';
-import { CassandraQuery, CassandraDataSourceOptions } from './models';
+import { CassandraQuery, CassandraDataSourceOptions, CassandraFilter, CassandraValueColumn } from './models';
 
 type Props = QueryEditorProps<CassandraDatasource, CassandraQuery, CassandraDataSourceOptions>;
 
+const aggregationOptions: Array<SelectableValue<string>> = [
+  { label: 'none', value: '' },
+  { label: 'avg', value: 'avg' },
+  { label: 'min', value: 'min' },
+  { label: 'max', value: 'max' },
+  { label: 'sum', value: 'sum' },
+  { label: 'count', value: 'count' },
+  { label: 'last', value: 'last' },
+];
+
+const reduceOptions: Array<SelectableValue<string>> = [
+  { label: 'none', value: '' },
+  { label: 'last', value: 'last' },
+  { label: 'avg', value: 'avg' },
+  { label: 'max', value: 'max' },
+  { label: 'min', value: 'min' },
+  { label: 'sum', value: 'sum' },
+  { label: 'count', value: 'count' },
+];
+
+const formatOptions: Array<SelectableValue<string>> = [
+  { label: 'Time series', value: '' },
+  { label: 'Wide time series', value: 'wide' },
+  { label: 'Table', value: 'table' },
+];
+
+// logs format is available for raw queries only
+const rawFormatOptions: Array<SelectableValue<string>> = [...formatOptions, { label: 'Logs', value: 'logs' }];
+
+const sortOptions: Array<SelectableValue<string>> = [
+  { label: 'Query order', value: '' },
+  { label: 'ID', value: 'id' },
+  { label: 'Alias', value: 'alias' },
+  { label: 'Last value', value: 'last' },
+];
+
+const bucketSizeOptions: Array<SelectableValue<string>> = [
+  { label: 'hour', value: 'hour' },
+  { label: 'day', value: 'day' },
+  { label: 'month', value: 'month' },
+];
+
+const filterOperatorOptions: Array<SelectableValue<string>> = [
+  { label: '=', value: '=' },
+  { label: 'IN', value: 'IN' },
+  { label: '<', value: '<' },
+  { label: '>', value: '>' },
+  { label: 'CONTAINS', value: 'CONTAINS' },
+];
+
 function selectable(value?: string): SelectableValue<string> {
   if (!value) {
     return {};
@@ -96,7 +146,7 @@ export class QueryEditor extends PureComponent<Props> {
         children?: React.ReactNode;
       }>
   ) {
-    let queryType: 'query' | 'alert' = 'query';
+    let queryType: CassandraQuery['queryType'] = this.props.query.queryType === 'trace' ? 'trace' : 'query';
     if (this.props.app && this.props.app === CoreApp.UnifiedAlerting) {
       queryType = 'alert';
     }
@@ -117,12 +167,17 @@ export class QueryEditor extends PureComponent<Props> {
       props.query.columnId !== '' &&
       props.query.valueId &&
       props.query.valueId !== ''
-      ) || (props.query.target && props.query.target !== ''))
+      ) || (props.query.target && props.query.target !== '') || (queryType === 'trace' && props.query.traceId))
     {
       this.props.onRunQuery();
     }
   }
 
+  onTraceIdChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, traceId: event.target.value });
+  };
+
   onQueryTextChange = (e: FormEvent<HTMLInputElement | HTMLTextAreaElement>) => {
     const { onChange, query } = this.props;
     const { value } = e.target as HTMLInputElement | HTMLTextAreaElement;
@@ -216,6 +271,126 @@ export class QueryEditor extends PureComponent<Props> {
     onChange({ ...query, instant: event.target.checked });
   };
 
+  onReduceChange = (event: SelectableValue<string>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, reduce: event.value || undefined });
+  };
+
+  onParallelChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, parallel: event.target.checked || undefined });
+  };
+
+  onNoCacheChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, noCache: event.target.checked || undefined });
+  };
+
+  onIncrementalChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, incremental: event.target.checked || undefined });
+  };
+
+  onMaxRowsChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, maxRows: Number(event.target.value) || undefined });
+  };
+
+  onValueColumnsChange = (index: number, valueColumn: CassandraValueColumn) => {
+    const { onChange, query } = this.props;
+    const valueColumns = [...(query.valueColumns || [])];
+    valueColumns[index] = valueColumn;
+    onChange({ ...query, valueColumns });
+  };
+
+  onValueColumnAdd = () => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, valueColumns: [...(query.valueColumns || []), { column: '' }] });
+  };
+
+  onValueColumnRemove = (index: number) => {
+    const { onChange, query } = this.props;
+    const valueColumns = (query.valueColumns || []).filter((_, i) => i !== index);
+    onChange({ ...query, valueColumns: valueColumns.length ? valueColumns : undefined });
+    this.props.onRunQuery();
+  };
+
+  onBucketColumnChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, bucketColumn: event.target.value || undefined, bucketSize: query.bucketSize || 'day' });
+  };
+
+  onBucketSizeChange = (event: SelectableValue<string>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, bucketSize: event.value });
+  };
+
+  onBucketFormatChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, bucketFormat: event.target.value || undefined });
+  };
+
+  onFilterChange = (index: number, filter: CassandraFilter) => {
+    const { onChange, query } = this.props;
+    const filters = [...(query.filters || [])];
+    filters[index] = filter;
+    onChange({ ...query, filters });
+  };
+
+  onFilterAdd = () => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, filters: [...(query.filters || []), { column: '', operator: '=', value: '' }] });
+  };
+
+  onFilterRemove = (index: number) => {
+    const { onChange, query } = this.props;
+    const filters = (query.filters || []).filter((_, i) => i !== index);
+    onChange({ ...query, filters: filters.length ? filters : undefined });
+    this.props.onRunQuery();
+  };
+
+  onAggregationChange = (event: SelectableValue<string>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, aggregation: event.value || undefined });
+  };
+
+  onFormatChange = (event: SelectableValue<string>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, format: event.value || undefined, pageState: undefined });
+  };
+
+  onSortByChange = (event: SelectableValue<string>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, sortBy: event.value || undefined });
+  };
+
+  onLogBodyColumnChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, logBodyColumn: event.target.value || undefined });
+  };
+
+  onLogLevelColumnChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, logLevelColumn: event.target.value || undefined });
+  };
+
+  // nextPageState returns the paging state of the next logs page returned with the last response.
+  nextPageState(): string | undefined {
+    const frame = this.props.data?.series.find((f) => f.refId === this.props.query.refId && f.meta?.custom?.pageState);
+    return frame?.meta?.custom?.pageState;
+  }
+
+  onPageStateChange = (pageState?: string) => {
+    const { onChange, onRunQuery, query } = this.props;
+    onChange({ ...query, pageState });
+    onRunQuery();
+  };
+
+  onExpandCollectionsChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onChange, query } = this.props;
+    onChange({ ...query, expandCollections: event.target.checked });
+  };
+
   render() {
     const options = this.props;
 
@@ -224,13 +399,14 @@ export class QueryEditor extends PureComponent<Props> {
       <div style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', margin: '10px 0' }}>
         <RadioButtonGroup
           options={[
-            { label: 'Configurator', value: false, icon: 'list-ul' },
-            { label: 'Query Editor',  value: true,  icon: 'pen'    },
+            { label: 'Configurator', value: 'configurator', icon: 'list-ul' },
+            { label: 'Query Editor', value: 'editor',       icon: 'pen'     },
+            { label: 'Trace',        value: 'trace',        icon: 'sitemap'   },
           ]}
-          value={options.query.rawQuery ?? false}
-          onChange={(val) => {
+          value={options.query.queryType === 'trace' ? 'trace' : options.query.rawQuery ? 'editor' : 'configurator'}
+          onChange={(mode) => {
             const { onChange, query } = this.props;
-            onChange({ ...query, rawQuery: val });
+            onChange({ ...query, rawQuery: mode === 'editor', queryType: mode === 'trace' ? 'trace' : 'query' });
           }}
         />
         <div style={{ display: 'flex', gap: '4px' }}>
@@ -259,7 +435,48 @@ export class QueryEditor extends PureComponent<Props> {
         </div>
       </div>
       <div>
-        {options.query.rawQuery && (
+        {options.query.queryType === 'trace' && (
+          <>
+            <InlineFieldRow>
+              <InlineField label="Keyspace" labelWidth={30} tooltip="Keyspace of the Jaeger schema, e.g. jaeger_v1_dc1">
+                <Select
+                  allowCustomValue={true}
+                  value={selectable(this.props.query.keyspace)}
+                  placeholder="keyspace name"
+                  onChange={this.onKeyspaceChange}
+                  options={this.state.keyspaceOptions}
+                  width={90}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField label="Table" labelWidth={30} tooltip="Spans table of the Jaeger schema">
+                <Select
+                  allowCustomValue={true}
+                  value={selectable(this.props.query.table)}
+                  placeholder="traces"
+                  onChange={this.onTableChange}
+                  options={this.state.tableOptions}
+                  width={90}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField label="Trace ID" labelWidth={30} tooltip="Hex ID of the trace">
+                <Input
+                  name="traceId"
+                  value={this.props.query.traceId || ''}
+                  onChange={this.onTraceIdChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={90}
+                />
+              </InlineField>
+            </InlineFieldRow>
+          </>
+        )}
+        {options.query.queryType !== 'trace' && options.query.rawQuery && (
           <>
             <InlineFieldRow>
               <InlineField
@@ -290,9 +507,44 @@ export class QueryEditor extends PureComponent<Props> {
                 />
               </InlineField>
             </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Max rows"
+                labelWidth={30}
+                tooltip="Overrides the datasource limit of rows returned by this query, -1 disables the limit"
+              >
+                <Input
+                  name="maxRows"
+                  type="number"
+                  step={1}
+                  placeholder="datasource default"
+                  value={this.props.query.maxRows ?? ''}
+                  onChange={this.onMaxRowsChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={30}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Expand collections"
+                labelWidth={30}
+                tooltip="Turn map columns into series labels and explode list and set columns into one row per element. By default collections are returned as JSON strings"
+              >
+                <InlineSwitch
+                  value={this.props.query.expandCollections}
+                  onChange={this.onExpandCollectionsChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                />
+              </InlineField>
+            </InlineFieldRow>
           </>
         )}
-        {!options.query.rawQuery && (
+        {options.query.queryType !== 'trace' && !options.query.rawQuery && (
           <>
             <InlineFieldRow>
               <InlineField label="Keyspace" labelWidth={30} tooltip="Specify keyspace to work with">
@@ -362,6 +614,42 @@ export class QueryEditor extends PureComponent<Props> {
                 />
               </InlineField>
             </InlineFieldRow>
+            {(this.props.query.valueColumns || []).map((valueColumn, index) => (
+              <InlineFieldRow key={index}>
+                <InlineField label="Additional Value Column" labelWidth={30} tooltip="One more numeric column of the same rows">
+                  <Select
+                    allowCustomValue={true}
+                    placeholder="value column"
+                    value={selectable(valueColumn.column)}
+                    options={this.state.valueColumnOptions}
+                    onChange={(event: SelectableValue<string>) =>
+                      this.onValueColumnsChange(index, { ...valueColumn, column: event.value || '' })
+                    }
+                    onBlur={() => {
+                      this.onRunQuery(this.props);
+                    }}
+                    width={45}
+                  />
+                </InlineField>
+                <Input
+                  placeholder="alias"
+                  value={valueColumn.alias || ''}
+                  onChange={(event: ChangeEvent<HTMLInputElement>) =>
+                    this.onValueColumnsChange(index, { ...valueColumn, alias: event.target.value || undefined })
+                  }
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={41}
+                />
+                <IconButton name="trash-alt" aria-label="Remove value column" onClick={() => this.onValueColumnRemove(index)} />
+              </InlineFieldRow>
+            ))}
+            <InlineFieldRow>
+              <Button variant="secondary" size="sm" icon="plus" onClick={this.onValueColumnAdd}>
+                Add value column
+              </Button>
+            </InlineFieldRow>
             <InlineFieldRow>
               <InlineField
                 label="ID Column"
@@ -400,6 +688,85 @@ export class QueryEditor extends PureComponent<Props> {
                 />
               </InlineField>
             </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Time Bucket Column"
+                labelWidth={30}
+                tooltip="Partition key column storing time buckets, e.g. date. All buckets covering the time range are queried"
+              >
+                <Input
+                  placeholder="bucket column"
+                  value={this.props.query.bucketColumn || ''}
+                  onChange={this.onBucketColumnChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={30}
+                />
+              </InlineField>
+              <Select
+                value={this.props.query.bucketSize || 'day'}
+                options={bucketSizeOptions}
+                onChange={this.onBucketSizeChange}
+                onBlur={() => {
+                  this.onRunQuery(this.props);
+                }}
+                disabled={!this.props.query.bucketColumn}
+                width={15}
+              />
+              <Input
+                placeholder="format, e.g. YYYY-MM-DD"
+                value={this.props.query.bucketFormat || ''}
+                onChange={this.onBucketFormatChange}
+                onBlur={() => {
+                  this.onRunQuery(this.props);
+                }}
+                disabled={!this.props.query.bucketColumn}
+                width={45}
+              />
+            </InlineFieldRow>
+            {(this.props.query.filters || []).map((filter, index) => (
+              <InlineFieldRow key={index}>
+                <InlineField label={index === 0 ? 'Where' : 'And'} labelWidth={30} tooltip="Additional condition on a table column">
+                  <Input
+                    placeholder="column"
+                    value={filter.column}
+                    onChange={(event: ChangeEvent<HTMLInputElement>) =>
+                      this.onFilterChange(index, { ...filter, column: event.target.value })
+                    }
+                    onBlur={() => {
+                      this.onRunQuery(this.props);
+                    }}
+                    width={30}
+                  />
+                </InlineField>
+                <Select
+                  value={filter.operator}
+                  options={filterOperatorOptions}
+                  onChange={(event: SelectableValue<string>) =>
+                    this.onFilterChange(index, { ...filter, operator: event.value || '=' })
+                  }
+                  width={15}
+                />
+                <Input
+                  placeholder="value, comma separated for IN"
+                  value={filter.value}
+                  onChange={(event: ChangeEvent<HTMLInputElement>) =>
+                    this.onFilterChange(index, { ...filter, value: event.target.value })
+                  }
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={41}
+                />
+                <IconButton name="trash-alt" aria-label="Remove filter" onClick={() => this.onFilterRemove(index)} />
+              </InlineFieldRow>
+            ))}
+            <InlineFieldRow>
+              <Button variant="secondary" size="sm" icon="plus" onClick={this.onFilterAdd}>
+                Add filter
+              </Button>
+            </InlineFieldRow>
             <InlineFieldRow>
               <InlineField label="Alias" labelWidth={30} tooltip="Series name override. Plain text or template using column names, e.g. `{{ column1 }}:{{ column2}}`">
                 <Input
@@ -414,6 +781,23 @@ export class QueryEditor extends PureComponent<Props> {
                 />
               </InlineField>
             </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Aggregation"
+                labelWidth={30}
+                tooltip="Downsample each series into time buckets sized to the panel interval and max data points"
+              >
+                <Select
+                  value={this.props.query.aggregation || ''}
+                  options={aggregationOptions}
+                  onChange={this.onAggregationChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={90}
+                />
+              </InlineField>
+            </InlineFieldRow>
             <InlineFieldRow>
               <InlineField
                   label="Instant"
@@ -429,6 +813,68 @@ export class QueryEditor extends PureComponent<Props> {
                 />
               </InlineField>
             </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Reduce"
+                labelWidth={30}
+                tooltip="Return a single value of each series over the time range instead of the time series, e.g. for alert rules"
+              >
+                <Select
+                  value={this.props.query.reduce || ''}
+                  options={reduceOptions}
+                  onChange={this.onReduceChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={90}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Query IDs in parallel"
+                labelWidth={30}
+                tooltip="Make a separate query for every ID value instead of a single IN query, reduces coordinator load for many IDs"
+              >
+                <InlineSwitch
+                  value={this.props.query.parallel}
+                  onChange={this.onParallelChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="No cache"
+                labelWidth={30}
+                tooltip="Always query fresh data even if the result cache is enabled for the datasource"
+              >
+                <InlineSwitch
+                  value={this.props.query.noCache}
+                  onChange={this.onNoCacheChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField
+                label="Incremental refresh"
+                labelWidth={30}
+                tooltip="Keep rows between dashboard refreshes and fetch only the ones newer than the previous query"
+              >
+                <InlineSwitch
+                  value={this.props.query.incremental}
+                  onChange={this.onIncrementalChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                />
+              </InlineField>
+            </InlineFieldRow>
             <InlineFieldRow>
               <InlineField
                 label="Allow filtering"
@@ -446,6 +892,95 @@ export class QueryEditor extends PureComponent<Props> {
             </InlineFieldRow>
           </>
         )}
+        {options.query.queryType !== 'trace' && (
+        <InlineFieldRow>
+          <InlineField
+            label="Format"
+            labelWidth={30}
+            tooltip="Time series make a frame per ID, wide time series make a single frame with a shared time field, table returns all rows as a single frame in the order returned by Cassandra, it is not available for aggregated and reduced queries"
+          >
+            <Select
+              value={this.props.query.format || ''}
+              options={options.query.rawQuery ? rawFormatOptions : formatOptions}
+              onChange={this.onFormatChange}
+              onBlur={() => {
+                this.onRunQuery(this.props);
+              }}
+              width={30}
+            />
+          </InlineField>
+        </InlineFieldRow>
+        )}
+        {options.query.queryType !== 'trace' && !options.query.format && (
+          <InlineFieldRow>
+            <InlineField
+              label="Sort series"
+              labelWidth={30}
+              tooltip="Order of the series in legend, the order of query result by default. Last value puts the highest values first"
+            >
+              <Select
+                value={this.props.query.sortBy || ''}
+                options={sortOptions}
+                onChange={this.onSortByChange}
+                onBlur={() => {
+                  this.onRunQuery(this.props);
+                }}
+                width={30}
+              />
+            </InlineField>
+          </InlineFieldRow>
+        )}
+        {options.query.queryType !== 'trace' && options.query.rawQuery && options.query.format === 'logs' && (
+          <>
+            <InlineFieldRow>
+              <InlineField
+                label="Log body column"
+                labelWidth={30}
+                tooltip="Column of the log line text. By default a column named message, body or line, otherwise the first text column"
+              >
+                <Input
+                  name="logBodyColumn"
+                  placeholder="message"
+                  value={this.props.query.logBodyColumn || ''}
+                  onChange={this.onLogBodyColumnChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={30}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <InlineField label="Log level column" labelWidth={30} tooltip="Column of the log line level, optional">
+                <Input
+                  name="logLevelColumn"
+                  value={this.props.query.logLevelColumn || ''}
+                  onChange={this.onLogLevelColumnChange}
+                  onBlur={() => {
+                    this.onRunQuery(this.props);
+                  }}
+                  width={30}
+                />
+              </InlineField>
+            </InlineFieldRow>
+            <InlineFieldRow>
+              <Button
+                variant="secondary"
+                size="sm"
+                icon="arrow-down"
+                disabled={!this.nextPageState()}
+                onClick={() => this.onPageStateChange(this.nextPageState())}
+              >
+                Load older logs
+              </Button>
+              {this.props.query.pageState && (
+                <Button variant="secondary" size="sm" icon="arrow-up" onClick={() => this.onPageStateChange(undefined)}>
+                  Newest logs
+                </Button>
+              )}
+            </InlineFieldRow>
+          </>
+        )}
       </div>
     </>);
   }
@@ -1,9 +1,12 @@
 import _ from 'lodash';
 import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
-import {DataQueryRequest, DataQueryResponse, DataSourceInstanceSettings} from '@grafana/data';
+import {AnnotationQuery, DataQueryRequest, DataQueryResponse, DataSourceInstanceSettings, ScopedVars} from '@grafana/data';
 import { CassandraQuery,CassandraVariableQuery, CassandraDataSourceOptions } from './models';
 import { Observable } from 'rxjs';
 
+// bindMacroRegexp matches $__bind(expression) macros of raw queries.
+const bindMacroRegexp = /\$__bind\(([^)]*)\)/g;
+
 export class CassandraDatasource extends DataSourceWithBackend<CassandraQuery, CassandraDataSourceOptions> {
   headers: any;
   id: number;
@@ -18,13 +21,25 @@ export class CassandraDatasource extends DataSourceWithBackend<CassandraQuery, C
 
     this.id = instanceSettings.id;
 
-    // annotations default behaviour
+    // annotations are raw CQL queries executed by the backend as the annotation query type
     // https://grafana.com/docs/grafana/latest/developers/plugins/create-a-grafana-plugin/extend-a-plugin/add-support-for-annotations/
-    this.annotations = {};
+    this.annotations = {
+      prepareQuery(anno: AnnotationQuery<CassandraQuery>): CassandraQuery | undefined {
+        if (!anno.target) {
+          return undefined;
+        }
+
+        return { ...anno.target, refId: anno.target.refId || 'Anno', queryType: 'annotation', rawQuery: true };
+      },
+    };
   }
 
   query(options: DataQueryRequest<CassandraQuery>): Observable<DataQueryResponse> {
-    if (this.isEditorMode(options)) {
+    if (options.targets[0].queryType === 'trace') {
+      if (!options.targets[0].keyspace || !options.targets[0].traceId) {
+        throw new Error('Skipping query execution while keyspace or trace ID is not filled');
+      }
+    } else if (this.isEditorMode(options)) {
       if (!this.isEditorCompleted(options)) {
         throw new Error('Skipping query execution while not all editor fields are filled');
       }
@@ -122,6 +137,27 @@ export class CassandraDatasource extends DataSourceWithBackend<CassandraQuery, C
     }
   }
 
+  // bindVariables moves values of $__bind(...) macros to the separate list, so the backend
+  // binds them to the prepared statement instead of putting them into the query text.
+  // Other template variables are interpolated as usual.
+  bindVariables(target: string | undefined, scopedVars: ScopedVars): Pick<CassandraQuery, 'target' | 'bindValues'> {
+    const bindValues: Array<string | string[]> = [];
+    const compiled = (target || '').replace(bindMacroRegexp, (_, expression: string) => {
+      let value: string | string[] = expression.trim();
+      getTemplateSrv().replace(value, scopedVars, (variableValue: string | string[]) => {
+        value = variableValue;
+        return '';
+      });
+      bindValues.push(value);
+      return `$__bind(${bindValues.length - 1})`;
+    });
+
+    return {
+      target: getTemplateSrv().replace(compiled, scopedVars, 'csv'),
+      bindValues: bindValues.length ? bindValues : undefined,
+    };
+  }
+
   buildQueryParameters(options: DataQueryRequest<CassandraQuery>): DataQueryRequest<CassandraQuery> {
     //remove placeholder targets
     options.targets = _.filter(options.targets, (target) => {
@@ -133,7 +169,7 @@ export class CassandraDatasource extends DataSourceWithBackend<CassandraQuery, C
         datasourceId: target.datasourceId,
         queryType: target.queryType,
 
-        target: getTemplateSrv().replace(target.target, options.scopedVars, 'csv'),
+        ...this.bindVariables(target.target, options.scopedVars),
         refId: target.refId,
         hide: target.hide,
         rawQuery: target.rawQuery,
@@ -146,6 +182,29 @@ export class CassandraDatasource extends DataSourceWithBackend<CassandraQuery, C
         valueId:  getTemplateSrv().replace(target.valueId, options.scopedVars, 'csv'),
         alias: target.alias,
         instant: target.instant,
+        expandCollections: target.expandCollections,
+        pageSize: target.pageSize,
+        maxRows: target.maxRows,
+        maxBytes: target.maxBytes,
+        aggregation: target.aggregation,
+        valueColumns: target.valueColumns,
+        bucketColumn: target.bucketColumn,
+        bucketSize: target.bucketSize,
+        bucketFormat: target.bucketFormat,
+        parallel: target.parallel,
+        noCache: target.noCache,
+        incremental: target.incremental,
+        format: target.format,
+        logBodyColumn: target.logBodyColumn,
+        logLevelColumn: target.logLevelColumn,
+        pageState: target.pageState,
+        traceId: getTemplateSrv().replace(target.traceId, options.scopedVars),
+        sortBy: target.sortBy,
+        reduce: target.reduce,
+        filters: target.filters?.map((filter) => ({
+          ...filter,
+          value: getTemplateSrv().replace(filter.value, options.scopedVars, 'csv'),
+        })),
       };
     });
 
@@ -3,6 +3,7 @@ import { DataSourceJsonData } from '@grafana/data';
 
 export interface CassandraQuery extends DataQuery {
   target?: string;
+  bindValues?: Array<string | string[]>;
   queryType: CassandraQueryType;
   filtering?: boolean;
   keyspace?: string;
@@ -15,6 +16,37 @@ export interface CassandraQuery extends DataQuery {
   rawQuery?: boolean;
   alias?: string;
   instant?: boolean;
+  expandCollections?: boolean;
+  pageSize?: number;
+  maxRows?: number;
+  maxBytes?: number;
+  aggregation?: string;
+  valueColumns?: CassandraValueColumn[];
+  filters?: CassandraFilter[];
+  bucketColumn?: string;
+  bucketSize?: string;
+  bucketFormat?: string;
+  parallel?: boolean;
+  noCache?: boolean;
+  incremental?: boolean;
+  format?: string;
+  logBodyColumn?: string;
+  logLevelColumn?: string;
+  pageState?: string;
+  traceId?: string;
+  sortBy?: string;
+  reduce?: string;
+}
+
+export interface CassandraValueColumn {
+  column: string;
+  alias?: string;
+}
+
+export interface CassandraFilter {
+  column: string;
+  operator: string;
+  value: string;
 }
 
 export interface CassandraVariableQuery {
@@ -33,6 +65,14 @@ export interface CassandraDataSourceOptions extends DataSourceJsonData {
   timeout: number;
   allowInsecureTLS: boolean;
   allowedAuthenticators?: string;
+  exactNumbers?: boolean;
+  pageSize?: number;
+  maxRows?: number;
+  maxBytes?: number;
+  concurrentQueries?: number;
+  cacheTTL?: number;
+  cacheSize?: number;
+  commentFieldConfigs?: boolean;
 }
 
-type CassandraQueryType = 'query' | 'alert';
+type CassandraQueryType = 'query' | 'alert' | 'annotation' | 'trace';